@@ -0,0 +1,372 @@
+package async
+
+import (
+	"fmt"
+	"github.com/ZenLiuCN/fn"
+	. "github.com/ZenLiuCN/glu/v3"
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+	"sync"
+)
+
+var (
+	FUTURE Type[*Future]
+	MODULE Module
+)
+
+// Future the result of an asynchronous operation, a Go function started by Go or a Lua function started by async.spawn
+type Future struct {
+	done    chan struct{}
+	once    sync.Once
+	value   any
+	err     error
+	results func(s *LState) []LValue //the values returned by await, default is value and error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Go start fn on a goroutine and returns the Future of its result
+func Go[T any](op func() (T, error)) *Future {
+	return run(newFuture(), op)
+}
+
+// GoOf same as Go, the value is pushed to Lua as userdata of Type t
+func GoOf[T any](t Type[T], op func() (T, error)) *Future {
+	f := newFuture()
+	f.results = func(s *LState) []LValue {
+		if f.err != nil {
+			return []LValue{LNil, LString(f.err.Error())}
+		}
+		return []LValue{t.NewValue(s, f.value.(T)), LNil}
+	}
+	return run(f, op)
+}
+
+func run[T any](f *Future, op func() (T, error)) *Future {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				f.resolve(nil, fmt.Errorf("panic: %v", r))
+			}
+		}()
+		v, err := op()
+		f.resolve(v, err)
+	}()
+	return f
+}
+
+// Start start op on a goroutine and push the Future, returns 1 as LGFunction result
+func Start[T any](s *LState, op func() (T, error)) int {
+	return FUTURE.New(s, Go(op))
+}
+
+// Func adapt an asynchronous operation to LGFunction which returns a Future.
+//
+// The prepare reads arguments on the LState, the returned function runs on a goroutine.
+func Func[T any](prepare func(s *LState) func() (T, error)) LGFunction {
+	return func(s *LState) int {
+		return Start(s, prepare(s))
+	}
+}
+
+// FuncOf same as Func, the value is pushed to Lua as userdata of Type t
+func FuncOf[T any](t Type[T], prepare func(s *LState) func() (T, error)) LGFunction {
+	return func(s *LState) int {
+		return FUTURE.New(s, GoOf(t, prepare(s)))
+	}
+}
+
+func (f *Future) resolve(v any, err error) {
+	f.once.Do(func() {
+		f.value, f.err = v, err
+		close(f.done)
+	})
+}
+
+// Done check if the Future is completed
+func (f *Future) Done() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait block until completed
+func (f *Future) Wait() (any, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// values the results pushed to Lua
+func (f *Future) values(s *LState) []LValue {
+	if f.results != nil {
+		return f.results(s)
+	}
+	if f.err != nil {
+		return []LValue{LNil, LString(f.err.Error())}
+	}
+	return []LValue{Pack(f.value, s), LNil}
+}
+
+// allOf the Future completed when all completed
+func allOf(fs []*Future) *Future {
+	f := newFuture()
+	f.results = func(s *LState) []LValue {
+		t := s.NewTable()
+		var err LValue = LNil
+		for i, x := range fs {
+			v := x.values(s)
+			t.RawSetInt(i+1, v[0])
+			if err == LNil && len(v) > 1 {
+				err = v[1]
+			}
+		}
+		return []LValue{t, err}
+	}
+	go func() {
+		for _, x := range fs {
+			<-x.done
+		}
+		f.resolve(nil, nil)
+	}()
+	return f
+}
+
+// anyOf the Future completed when any completed, fs must not be empty
+func anyOf(fs []*Future) *Future {
+	f := newFuture()
+	cases := make([]reflect.SelectCase, len(fs))
+	for i, x := range fs {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(x.done)}
+	}
+	go func() {
+		i, _, _ := reflect.Select(cases)
+		f.results = func(s *LState) []LValue {
+			return append([]LValue{LNumber(i + 1)}, fs[i].values(s)...)
+		}
+		f.resolve(nil, nil)
+	}()
+	return f
+}
+
+// task a Lua function running in coroutine
+type task struct {
+	co      *LState
+	fn      *LFunction
+	args    []LValue //the values for next resume
+	wait    *Future  //the Future waiting for, nil if ready
+	future  *Future
+	running bool
+}
+
+// scheduler the tasks of a LState, shared by its coroutines
+type scheduler struct {
+	tasks   []*task
+	current *task
+}
+
+const schedulerKey = "_ASYNC"
+
+func schedulerOf(s *LState, create bool) *scheduler {
+	if ud, ok := s.G.Registry.RawGetString(schedulerKey).(*LUserData); ok {
+		return ud.Value.(*scheduler)
+	}
+	if !create {
+		return nil
+	}
+	c := &scheduler{}
+	ud := s.NewUserData()
+	ud.Value = c
+	s.G.Registry.RawSetString(schedulerKey, ud)
+	return c
+}
+
+// spawn run fn as a task
+func (c *scheduler) spawn(s *LState, fn *LFunction, args []LValue) *Future {
+	co, _ := s.NewThread()
+	t := &task{co: co, fn: fn, args: args, future: newFuture()}
+	c.tasks = append(c.tasks, t)
+	return t.future
+}
+
+// step resume the ready tasks, returns false if none is ready
+func (c *scheduler) step(s *LState) (progress bool) {
+	tasks := append([]*task(nil), c.tasks...)
+	for _, t := range tasks {
+		if t.running || t.future.Done() {
+			continue
+		}
+		if t.wait != nil {
+			if !t.wait.Done() {
+				continue
+			}
+			t.args = t.wait.values(s)
+			t.wait = nil
+		}
+		progress = true
+		prev := c.current
+		c.current, t.running = t, true
+		st, err, values := s.Resume(t.co, t.fn, t.args...)
+		c.current, t.running = prev, false
+		switch st {
+		case ResumeYield:
+			if len(values) > 0 {
+				if ud, ok := values[0].(*LUserData); ok {
+					if f, ok := ud.Value.(*Future); ok {
+						t.wait = f
+						continue
+					}
+				}
+			}
+			t.args = nil
+		case ResumeOK:
+			var v any
+			if len(values) > 0 {
+				v = values[0]
+			}
+			c.finish(t, v, nil)
+		default:
+			c.finish(t, nil, err)
+		}
+	}
+	return
+}
+func (c *scheduler) finish(t *task, v any, err error) {
+	t.future.resolve(v, err)
+	for i, x := range c.tasks {
+		if x == t {
+			c.tasks = append(c.tasks[:i], c.tasks[i+1:]...)
+			break
+		}
+	}
+}
+
+// wait run the tasks until f completed, raise error if the context of LState is done
+func (c *scheduler) wait(s *LState, f *Future) {
+	ctx := s.Context()
+	for !f.Done() {
+		if c.step(s) {
+			continue
+		}
+		cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.done)}}
+		if ctx != nil {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+		}
+		for _, t := range c.tasks {
+			if t.wait != nil {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(t.wait.done)})
+			}
+		}
+		reflect.Select(cases)
+		interrupted(s)
+	}
+}
+
+// block until f completed, raise error if the context of LState is done
+func block(s *LState, f *Future) {
+	ctx := s.Context()
+	if ctx == nil {
+		<-f.done
+		return
+	}
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		interrupted(s)
+	}
+}
+
+// interrupted raise error if the context of LState is done
+func interrupted(s *LState) {
+	if ctx := s.Context(); ctx != nil && ctx.Err() != nil {
+		s.RaiseError("await interrupted: %s", ctx.Err())
+	}
+}
+
+// await yield to scheduler in task, else run the tasks until f completed
+func await(s *LState, f *Future) int {
+	c := schedulerOf(s, false)
+	if c != nil && c.current != nil && c.current.co == s {
+		FUTURE.New(s, f)
+		return s.Yield(s.Get(-1))
+	}
+	if c != nil {
+		c.wait(s, f)
+		if len(c.tasks) == 0 && c.current == nil {
+			s.G.Registry.RawSetString(schedulerKey, LNil)
+		}
+	} else {
+		block(s, f)
+	}
+	values := f.values(s)
+	for _, v := range values {
+		s.Push(v)
+	}
+	return len(values)
+}
+
+func checkFutures(s *LState, n int) []*Future {
+	t := s.CheckTable(n)
+	fs := make([]*Future, 0, t.Len())
+	for i := 1; i <= t.Len(); i++ {
+		ud, ok := t.RawGetInt(i).(*LUserData)
+		if !ok {
+			s.ArgError(n, "Future expected")
+			return nil
+		}
+		fs = append(fs, FUTURE.CheckUserData(ud, s))
+	}
+	return fs
+}
+
+func init() {
+	// drop the tasks never awaited
+	OnReset(func(l *LState) {
+		l.G.Registry.RawSetString(schedulerKey, LNil)
+	})
+	FUTURE = NewTypeCast(func(a any) (v *Future, ok bool) { v, ok = a.(*Future); return }, "Future", `the result of asynchronous operation`, false, "", nil).
+		AddMethodCast("done", `()bool 	 check if completed`, func(s *LState, f *Future) int {
+			s.Push(LBool(f.Done()))
+			return 1
+		}).
+		AddMethodCast("await", `()any?,string? 	 wait for the value and error, same as async.await`, await)
+	MODULE = NewModule("async", `async run Go operations on goroutines and Lua functions as tasks, the tasks run while awaiting.
+local async=require('async')
+local a=async.spawn(function(x) return x*2 end,1)
+local b=async.spawn(function(x) return x*3 end,2)
+local values,err=async.awaitAll({a,b})
+Note: do not tail call await in task, such as 'return async.await(f)', use 'local v=async.await(f) return v' instead.
+`, true).
+		AddFunc("spawn", `(fn function,...any)Future 	 run function as task with arguments, the Future resolves to first return value`,
+			func(s *LState) int {
+				f := s.CheckFunction(1)
+				args := make([]LValue, 0, s.GetTop()-1)
+				for i := 2; i <= s.GetTop(); i++ {
+					args = append(args, s.Get(i))
+				}
+				return FUTURE.New(s, schedulerOf(s, true).spawn(s, f, args))
+			}).
+		AddFunc("await", `(f Future)any?,string? 	 wait for the value and error, yield in task`,
+			func(s *LState) int {
+				return await(s, FUTURE.Check(s, 1))
+			}).
+		AddFunc("awaitAll", `(fs {Future})table,string? 	 wait for all, returns values in order and the first error`,
+			func(s *LState) int {
+				return await(s, allOf(checkFutures(s, 1)))
+			}).
+		AddFunc("awaitAny", `(fs {Future})int,any?,string? 	 wait for any, returns index, value and error of the first completed`,
+			func(s *LState) int {
+				fs := checkFutures(s, 1)
+				if len(fs) == 0 {
+					s.ArgError(1, "empty Future list")
+					return 0
+				}
+				return await(s, anyOf(fs))
+			}).
+		AddModule(FUTURE)
+	fn.Panic(Register(MODULE))
+}
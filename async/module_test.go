@@ -0,0 +1,115 @@
+package async
+
+import (
+	"context"
+	"errors"
+	. "github.com/ZenLiuCN/glu/v3"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+	"time"
+)
+
+func init() {
+	_ = Register(NewModule("asyncTest", ``, true).
+		AddFunc("delay", `(ms int,value string)Future`, Func(func(s *LState) func() (string, error) {
+			d, v := time.Duration(s.CheckInt(1))*time.Millisecond, s.CheckString(2)
+			return func() (string, error) {
+				time.Sleep(d)
+				if v == "" {
+					return "", errors.New("empty")
+				}
+				return v, nil
+			}
+		})))
+}
+
+func TestAwait(t *testing.T) {
+	start := time.Now()
+	if err := ExecuteCode(`
+		local async=require('async')
+		local test=require('asyncTest')
+		local v,err=async.await(test.delay(10,'a'))
+		assert(v=='a' and err==nil)
+		v,err=test.delay(1,''):await()
+		assert(v==nil and err=='empty')
+		local values,err=async.awaitAll({test.delay(50,'x'),test.delay(50,'y'),test.delay(50,'z')})
+		assert(values[1]=='x' and values[3]=='z' and err==nil)
+		local i,v=async.awaitAny({test.delay(200,'slow'),test.delay(1,'fast')})
+		assert(i==2 and v=='fast')
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Fatal("should overlap", d)
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	if err := ExecuteCode(`
+		local async=require('async')
+		local test=require('asyncTest')
+		local order={}
+		local function job(name,ms)
+			table.insert(order,name..'>')
+			local v=async.await(test.delay(ms,name))
+			table.insert(order,v..'<')
+			return v..'!'
+		end
+		local a=async.spawn(job,'a',40)
+		local b=async.spawn(job,'b',10)
+		local c=async.spawn(function() error('boom') end)
+		local values=async.awaitAll({a,b})
+		assert(values[1]=='a!' and values[2]=='b!')
+		assert(table.concat(order,',')=='a>,b>,b<,a<', table.concat(order,','))
+		local v,err=async.await(c)
+		assert(v==nil and string.find(err,'boom'))
+		local outer=async.spawn(function()
+			local inner=async.spawn(job,'i',1)
+			return async.await(inner)..'?'
+		end)
+		assert(async.await(outer)=='i!?')
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetTasks(t *testing.T) {
+	p := MustNewPool(PoolConfig{PoolOptions: PoolOptions{MaxSize: 1}})
+	defer p.Shutdown()
+	ctx := context.Background()
+	if err := p.ExecuteCode(ctx, `require('async').spawn(function() LEAKED='x' end)`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ExecuteCode(ctx, `
+		local async=require('async')
+		assert(async.await(async.spawn(function() return 1 end))==1)
+		assert(LEAKED==nil,'stale task should not run')
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAwaitContext(t *testing.T) {
+	if err := ExecuteCode(`
+		local async=require('async')
+		assert(not pcall(async.awaitAny,{}))
+		local values=async.awaitAll({})
+		assert(#values==0)
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{
+		`require('async').await(require('asyncTest').delay(5000,'slow'))`,
+		`local async=require('async')
+		local t=async.spawn(function() local v=async.await(require('asyncTest').delay(5000,'slow')) return v end)
+		async.await(t)`,
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		err := ExecuteCodeWithContext(ctx, code, 0, 0, nil, nil)
+		cancel()
+		if !errors.Is(err, ErrTimeout) || time.Since(start) > time.Second {
+			t.Fatal("should abort by context", err, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package glu
+
+import (
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	//BindTagName the struct tag for field name of BindType, `lua:"-"` will skip the field
+	BindTagName = "lua"
+	//BindHelpTagName the struct tag for field help of BindType
+	BindHelpTagName = "help"
+)
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	lValueType = reflect.TypeOf((*LValue)(nil)).Elem()
+	bound      sync.Map // reflect.Type => boundType
+)
+
+// boundType the registered type of BindType
+type boundType struct {
+	name string
+	pack func(s *LState, v any) LValue
+}
+
+// BindType create a BaseType by reflect on exported methods and fields of T.
+//
+// Methods are exposed as instance methods with lower camel case names (`SendString` => `sendString`),
+// a trailing error result will be raised as Lua error.
+//
+// Fields are exposed as properties: `v.name` fetch the value, `v.name=value` set the value (only for pointer of struct).
+// The field name and help can be override with struct tag `lua:"name" help:"some help"`.
+//
+// @helps optional help override map, key is the Lua name of method or field, `new` for constructor.
+//
+// When T is a pointer of struct, a constructor `new()` with zero value is also defined.
+func BindType[T any](name string, help string, top bool, helps map[string]string) *BaseType[T] {
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	var ctor func(*LState) T
+	ctorHelp := ""
+	if rt.Kind() == reflect.Pointer && rt.Elem().Kind() == reflect.Struct {
+		ctor = func(s *LState) T {
+			return reflect.New(rt.Elem()).Interface().(T)
+		}
+		ctorHelp = "()" + name
+		if h, ok := helps["new"]; ok {
+			ctorHelp = h
+		}
+	}
+	m := NewTypeCast[T](func(a any) (v T, ok bool) { v, ok = a.(T); return }, name, help, top, ctorHelp, ctor)
+	bound.Store(rt, boundType{name, func(s *LState, v any) LValue {
+		return m.NewValue(s, v.(T))
+	}})
+	iface := rt.Kind() == reflect.Interface
+	for i := 0; i < rt.NumMethod(); i++ {
+		mt := rt.Method(i)
+		if !mt.IsExported() {
+			continue
+		}
+		idx := i
+		n := luaName(mt.Name)
+		h, ok := helps[n]
+		if !ok && iface {
+			h = signature(mt.Type, 0)
+		} else if !ok {
+			h = signature(mt.Type, 1)
+		}
+		m.AddMethodCast(n, h, func(s *LState, v T) int {
+			if iface {
+				return invoke(s, reflect.ValueOf(v).MethodByName(mt.Name), 2, ErrorRaise)
+			}
+			return invoke(s, reflect.ValueOf(v).Method(idx), 2, ErrorRaise)
+		})
+	}
+	st := rt
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() == reflect.Struct {
+		for i := 0; i < st.NumField(); i++ {
+			f := st.Field(i)
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			n := luaName(f.Name)
+			if tag, ok := f.Tag.Lookup(BindTagName); ok {
+				tag, _, _ = strings.Cut(tag, ",")
+				if tag == "-" {
+					continue
+				} else if tag != "" {
+					n = tag
+				}
+			}
+			if _, ok := m.methods[n]; ok {
+				continue
+			} else if _, ok = m.properties[n]; ok {
+				continue
+			}
+			h, ok := helps[n]
+			if !ok {
+				h = typeName(f.Type)
+				if fh := f.Tag.Get(BindHelpTagName); fh != "" {
+					h += " \t " + fh
+				}
+			}
+			idx := f.Index
+			ft := f.Type
+			var setter func(s *LState, v T, value LValue)
+			if rt.Kind() == reflect.Pointer {
+				setter = func(s *LState, v T, value LValue) {
+					rv, ok := fromLValue(value, ft)
+					if !ok {
+						s.ArgError(3, "require type "+typeName(ft))
+						return
+					}
+					reflect.Indirect(reflect.ValueOf(v)).FieldByIndex(idx).Set(rv)
+				}
+			}
+			m.AddPropertyCast(n, h, func(s *LState, v T) LValue {
+				return packReflect(s, reflect.Indirect(reflect.ValueOf(v)).FieldByIndex(idx))
+			}, setter)
+		}
+	}
+	return m
+}
+
+// luaName convert exported Go name to lower camel case: ID => id, HTTPServer => httpServer, Name => name
+func luaName(n string) string {
+	r := []rune(n)
+	for i := 0; i < len(r); i++ {
+		if !unicode.IsUpper(r[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// typeName the name of type used in help
+func typeName(t reflect.Type) string {
+	if t == lValueType {
+		return "any"
+	}
+	if b, ok := bound.Load(t); ok {
+		return b.(boundType).name
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "{" + typeName(t.Elem()) + "}"
+	case reflect.Map:
+		return "table"
+	case reflect.Func:
+		return "function"
+	case reflect.Interface:
+		return "any"
+	default:
+		return "userdata"
+	}
+}
+
+// signature generate help signature of function type, skip leading n parameters (receiver)
+func signature(t reflect.Type, skip int) string {
+	b := new(strings.Builder)
+	b.WriteRune('(')
+	for i := skip; i < t.NumIn(); i++ {
+		if i > skip {
+			b.WriteRune(',')
+		}
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			b.WriteString(typeName(t.In(i).Elem()))
+			b.WriteString(" ...")
+		} else {
+			b.WriteString(typeName(t.In(i)))
+		}
+	}
+	b.WriteRune(')')
+	n := t.NumOut()
+	if n > 0 && t.Out(n-1) == errorType {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteRune(',')
+		}
+		b.WriteString(typeName(t.Out(i)))
+	}
+	return b.String()
+}
+
+// invoke call the function with arguments start from stack index, push results.
+//
+// a trailing error result is processed by the ErrorConvention.
+func invoke(s *LState, fn reflect.Value, from int, conv ErrorConvention) int {
+	t := fn.Type()
+	n := t.NumIn()
+	args := make([]reflect.Value, 0, n)
+	for i := 0; i < n; i++ {
+		if t.IsVariadic() && i == n-1 {
+			for j := from + i; j <= s.GetTop(); j++ {
+				args = append(args, checkReflect(s, j, t.In(i).Elem()))
+			}
+			break
+		}
+		args = append(args, checkReflect(s, from+i, t.In(i)))
+	}
+	out := fn.Call(args)
+	if k := len(out); k > 0 && t.Out(k-1) == errorType {
+		e := out[k-1]
+		out = out[:k-1]
+		if !e.IsNil() {
+			if conv == ErrorRaise {
+				s.RaiseError("%s", e.Interface().(error).Error())
+				return 0
+			}
+			for range out {
+				s.Push(LNil)
+			}
+			s.Push(LString(e.Interface().(error).Error()))
+			return k
+		}
+		if conv == ErrorReturn {
+			for _, v := range out {
+				s.Push(packReflect(s, v))
+			}
+			s.Push(LNil)
+			return k
+		}
+	}
+	for _, v := range out {
+		s.Push(packReflect(s, v))
+	}
+	return len(out)
+}
+
+// checkReflect check the value on stack and convert to the type. Otherwise, an error raised.
+func checkReflect(s *LState, n int, t reflect.Type) reflect.Value {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(CheckString(s, n)).Convert(t)
+	case reflect.Bool:
+		return reflect.ValueOf(CheckBool(s, n)).Convert(t)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := CheckInt64(s, n)
+		v := reflect.ValueOf(i).Convert(t)
+		if v.Int() != i {
+			s.ArgError(n, "number overflow of "+t.String())
+		}
+		return v
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i := CheckInt64(s, n)
+		v := reflect.ValueOf(i).Convert(t)
+		if i < 0 || v.Uint() != uint64(i) {
+			s.ArgError(n, "number overflow of "+t.String())
+		}
+		return v
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(CheckFloat64(s, n)).Convert(t)
+	default:
+		return Check(s, n, func(v LValue) (reflect.Value, bool) {
+			return fromLValue(v, t)
+		})
+	}
+}
+
+// fromLValue convert LValue to the type
+func fromLValue(v LValue, t reflect.Type) (r reflect.Value, ok bool) {
+	if t == lValueType {
+		return reflect.ValueOf(&v).Elem(), true
+	}
+	if v == LNil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), true
+		}
+		return
+	}
+	if rv := reflect.ValueOf(v); t.Kind() != reflect.Interface && rv.Type().AssignableTo(t) {
+		return rv, true
+	}
+	switch v.Type() {
+	case LTUserData:
+		if u := v.(*LUserData).Value; u != nil {
+			if rv := reflect.ValueOf(u); rv.Type().AssignableTo(t) {
+				return rv, true
+			}
+		}
+		return
+	case LTTable:
+		tb := v.(*LTable)
+		switch t.Kind() {
+		case reflect.Slice:
+			r = reflect.MakeSlice(t, 0, tb.Len())
+			for i := 1; i <= tb.Len(); i++ {
+				e, ok := fromLValue(tb.RawGetInt(i), t.Elem())
+				if !ok {
+					return r, false
+				}
+				r = reflect.Append(r, e)
+			}
+			return r, true
+		case reflect.Map:
+			r = reflect.MakeMap(t)
+			ok = true
+			tb.ForEach(func(k LValue, val LValue) {
+				if !ok {
+					return
+				}
+				var kv, vv reflect.Value
+				if kv, ok = fromLValue(k, t.Key()); !ok {
+					return
+				}
+				if vv, ok = fromLValue(val, t.Elem()); !ok {
+					return
+				}
+				r.SetMapIndex(kv, vv)
+			})
+			return
+		case reflect.Interface:
+			if t.NumMethod() == 0 {
+				m, _ := TableUnpack(tb, false, nil)
+				return reflect.ValueOf(m), true
+			}
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if v.Type() == LTString {
+			return reflect.ValueOf(v.String()).Convert(t), true
+		}
+	case reflect.Bool:
+		if v.Type() == LTBool {
+			return reflect.ValueOf(v == LTrue).Convert(t), true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := integral(v); ok {
+			r = reflect.ValueOf(i).Convert(t)
+			return r, r.Int() == i
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, ok := integral(v); ok && i >= 0 {
+			r = reflect.ValueOf(i).Convert(t)
+			return r, r.Uint() == uint64(i)
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Type() == LTNumber {
+			return reflect.ValueOf(float64(v.(LNumber))).Convert(t), true
+		}
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return reflect.ValueOf(Raw(v)), true
+		}
+	}
+	return
+}
+
+// integral the number without fractional part, same as CheckInt64
+func integral(v LValue) (i int64, ok bool) {
+	if n, ok := v.(LNumber); ok {
+		f := float64(n)
+		i = int64(f)
+		return i, f == float64(i)
+	}
+	return
+}
+
+// packReflect pack value to LValue, see Pack
+func packReflect(s *LState, v reflect.Value) LValue {
+	return (&packer{s: s, visiting: make(map[packRef]bool)}).pack(v)
+}
@@ -0,0 +1,111 @@
+package glu
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+	"testing"
+)
+
+type bindPoint struct {
+	X      int
+	Y      int    `help:"the y axis"`
+	Label  string `lua:"tag"`
+	Hidden string `lua:"-"`
+}
+
+func (p *bindPoint) Add(o *bindPoint) *bindPoint {
+	return &bindPoint{X: p.X + o.X, Y: p.Y + o.Y}
+}
+func (p *bindPoint) Scale(n float64) (*bindPoint, error) {
+	if n == 0 {
+		return nil, errors.New("zero scale")
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("negative scale %%d: %v", n)
+	}
+	return &bindPoint{X: int(float64(p.X) * n), Y: int(float64(p.Y) * n)}, nil
+}
+func (p *bindPoint) String() string {
+	return fmt.Sprintf("(%d,%d)", p.X, p.Y)
+}
+func (p *bindPoint) Sum(v ...int) (r int) {
+	for _, i := range v {
+		r += i
+	}
+	return
+}
+
+func init() {
+	fn.Panic(Register(BindType[*bindPoint]("Point", `reflect bound point`, true, map[string]string{
+		"add": "(Point)Point 	 add two point",
+	})))
+}
+
+func TestLuaName(t *testing.T) {
+	for s, w := range map[string]string{
+		"ID":         "id",
+		"HTTPServer": "httpServer",
+		"Name":       "name",
+		"SendString": "sendString",
+		"X":          "x",
+	} {
+		if n := luaName(s); n != w {
+			t.Errorf("luaName(%s)=%s want %s", s, n, w)
+		}
+	}
+}
+
+func TestBindType(t *testing.T) {
+	err := ExecuteCode(`
+		print(Point.help('?'))
+		local p=Point.new()
+		p.x=1
+		p.y=2
+		p.tag='a'
+		assert(p.x==1 and p.y==2 and p.tag=='a')
+		assert(not pcall(function() return p.hidden end))
+		local q=p:add(p)
+		assert(q.x==2 and q.y==4)
+		assert(q:string()=='(2,4)')
+		assert(q:scale(2).x==4)
+		assert(q:sum(1,2,3)==6)
+		local ok,err=pcall(q.scale,q,0)
+		assert(not ok and string.find(err,'zero scale'))
+		ok,err=pcall(q.scale,q,-1)
+		assert(not ok and string.find(err,'negative scale %d: -1',1,true),err)
+		ok,err=pcall(function() p.x='a' end)
+		assert(not ok)
+	`, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromLValueNumber(t *testing.T) {
+	for _, c := range []struct {
+		v  LValue
+		t  any
+		ok bool
+	}{
+		{LNumber(1), int(0), true},
+		{LNumber(1.5), int(0), false},
+		{LNumber(127), int8(0), true},
+		{LNumber(128), int8(0), false},
+		{LNumber(-1), uint(0), false},
+		{LNumber(255), uint8(0), true},
+		{LNumber(256), uint8(0), false},
+		{LNumber(0.5), uint(0), false},
+		{LNumber(1.5), float32(0), true},
+		{LString("1"), int(0), false},
+	} {
+		r, ok := fromLValue(c.v, reflect.TypeOf(c.t))
+		if ok != c.ok {
+			t.Errorf("fromLValue(%v,%T) should be %v", c.v, c.t, c.ok)
+		} else if ok && fmt.Sprint(r.Interface()) != c.v.String() {
+			t.Errorf("fromLValue(%v,%T)=%v", c.v, c.t, r.Interface())
+		}
+	}
+}
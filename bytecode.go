@@ -0,0 +1,348 @@
+package glu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"hash/crc32"
+	"io"
+	"math"
+	"reflect"
+)
+
+// BytecodeVersion the version of format written by DumpChunk
+const BytecodeVersion uint16 = 1
+
+var (
+	bytecodeMagic = []byte("GLUC")
+	// ErrBytecodeVersion the bytecode is written by an unsupported version
+	ErrBytecodeVersion = errors.New("unsupported bytecode version")
+	// ErrBytecodeCorrupt the bytecode is not valid
+	ErrBytecodeCorrupt = errors.New("corrupt bytecode")
+)
+
+const (
+	rkBit   = 1 << 8           //the flag of constant index in RK operand
+	sbxBias = (1<<18 - 1) >> 1 //the bias of signed Bx operand
+)
+
+const (
+	constNil byte = iota
+	constFalse
+	constTrue
+	constNumber
+	constString
+)
+
+// DumpChunk write the FunctionProto (constants, nested protos and debug info) into w in versioned binary format.
+//
+// Format: magic `GLUC`, version uint16, payload, crc32 of payload. Load it back by LoadChunk.
+func DumpChunk(w io.Writer, p *FunctionProto) error {
+	e := &chunkEncoder{}
+	if err := e.proto(p); err != nil {
+		return err
+	}
+	n := len(bytecodeMagic)
+	b := make([]byte, n+2+e.Len()+4)
+	copy(b, bytecodeMagic)
+	binary.BigEndian.PutUint16(b[n:], BytecodeVersion)
+	copy(b[n+2:], e.Bytes())
+	binary.BigEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(e.Bytes()))
+	_, err := w.Write(b)
+	return err
+}
+
+// LoadChunk read the FunctionProto written by DumpChunk.
+//
+// Returns ErrBytecodeVersion if version mismatch, ErrBytecodeCorrupt if input is invalid.
+func LoadChunk(r io.Reader) (p *FunctionProto, err error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	n := len(bytecodeMagic)
+	if len(b) < n+2+4 || !bytes.Equal(b[:n], bytecodeMagic) {
+		return nil, fmt.Errorf("%w: invalid header", ErrBytecodeCorrupt)
+	}
+	if v := binary.BigEndian.Uint16(b[n:]); v != BytecodeVersion {
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrBytecodeVersion, v, BytecodeVersion)
+	}
+	payload := b[n+2 : len(b)-4]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(b[len(b)-4:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrBytecodeCorrupt)
+	}
+	if !stringConstantsOk {
+		return nil, fmt.Errorf("%w: FunctionProto of gopher-lua is not supported", ErrBytecodeVersion)
+	}
+	d := &chunkDecoder{b: payload}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && errors.Is(e, ErrBytecodeCorrupt) {
+				p, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
+	p = d.proto()
+	if len(d.b) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrBytecodeCorrupt, len(d.b))
+	}
+	return p, nil
+}
+
+type chunkEncoder struct {
+	bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *chunkEncoder) int(v int) {
+	e.Write(e.tmp[:binary.PutVarint(e.tmp[:], int64(v))])
+}
+func (e *chunkEncoder) string(s string) {
+	e.int(len(s))
+	e.WriteString(s)
+}
+func (e *chunkEncoder) proto(p *FunctionProto) error {
+	e.string(p.SourceName)
+	e.int(p.LineDefined)
+	e.int(p.LastLineDefined)
+	e.Write([]byte{p.NumUpvalues, p.NumParameters, p.IsVarArg, p.NumUsedRegisters})
+	e.int(len(p.Code))
+	for _, c := range p.Code {
+		binary.BigEndian.PutUint32(e.tmp[:], c)
+		e.Write(e.tmp[:4])
+	}
+	e.int(len(p.Constants))
+	for _, c := range p.Constants {
+		switch v := c.(type) {
+		case *LNilType:
+			e.WriteByte(constNil)
+		case LBool:
+			if v {
+				e.WriteByte(constTrue)
+			} else {
+				e.WriteByte(constFalse)
+			}
+		case LNumber:
+			e.WriteByte(constNumber)
+			binary.BigEndian.PutUint64(e.tmp[:], math.Float64bits(float64(v)))
+			e.Write(e.tmp[:8])
+		case LString:
+			e.WriteByte(constString)
+			e.string(string(v))
+		default:
+			return fmt.Errorf("unsupported constant %s of %s", c.Type(), p.SourceName)
+		}
+	}
+	e.int(len(p.FunctionPrototypes))
+	for _, f := range p.FunctionPrototypes {
+		if err := e.proto(f); err != nil {
+			return err
+		}
+	}
+	e.int(len(p.DbgSourcePositions))
+	for _, v := range p.DbgSourcePositions {
+		e.int(v)
+	}
+	e.int(len(p.DbgLocals))
+	for _, v := range p.DbgLocals {
+		e.string(v.Name)
+		e.int(v.StartPc)
+		e.int(v.EndPc)
+	}
+	e.int(len(p.DbgCalls))
+	for _, v := range p.DbgCalls {
+		e.string(v.Name)
+		e.int(v.Pc)
+	}
+	e.int(len(p.DbgUpvalues))
+	for _, v := range p.DbgUpvalues {
+		e.string(v)
+	}
+	return nil
+}
+
+// stringConstants the unexported string constants of FunctionProto used by GETGLOBAL and SETGLOBAL, which is filled
+// by compiler. LoadChunk rejects all input if gopher-lua changes it.
+var stringConstants, stringConstantsOk = func() (reflect.StructField, bool) {
+	f, ok := reflect.TypeOf(FunctionProto{}).FieldByName("stringConstants")
+	return f, ok && f.Type == reflect.TypeOf([]string(nil))
+}()
+
+// chunkDecoder decode the payload, panic with ErrBytecodeCorrupt on invalid input
+type chunkDecoder struct {
+	b []byte
+}
+
+func (d *chunkDecoder) fail(what string) {
+	panic(fmt.Errorf("%w: invalid %s", ErrBytecodeCorrupt, what))
+}
+func (d *chunkDecoder) next(n int, what string) []byte {
+	if n < 0 || n > len(d.b) {
+		d.fail(what)
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+func (d *chunkDecoder) int(what string) int {
+	v, n := binary.Varint(d.b)
+	if n <= 0 || v > math.MaxInt32 || v < math.MinInt32 {
+		d.fail(what)
+	}
+	d.b = d.b[n:]
+	return int(v)
+}
+
+// size read a length, each element takes at least min bytes
+func (d *chunkDecoder) size(min int, what string) int {
+	n := d.int(what)
+	if n < 0 || n*min > len(d.b) {
+		d.fail(what)
+	}
+	return n
+}
+func (d *chunkDecoder) string(what string) string {
+	return string(d.next(d.size(1, what), what))
+}
+func (d *chunkDecoder) proto() *FunctionProto {
+	p := &FunctionProto{}
+	p.SourceName = d.string("source name")
+	p.LineDefined = d.int("line defined")
+	p.LastLineDefined = d.int("last line defined")
+	h := d.next(4, "header")
+	p.NumUpvalues, p.NumParameters, p.IsVarArg, p.NumUsedRegisters = h[0], h[1], h[2], h[3]
+	p.Code = make([]uint32, d.size(4, "code"))
+	for i := range p.Code {
+		p.Code[i] = binary.BigEndian.Uint32(d.next(4, "code"))
+	}
+	p.Constants = make([]LValue, d.size(1, "constants"))
+	strs := make([]string, len(p.Constants))
+	for i := range p.Constants {
+		switch d.next(1, "constant")[0] {
+		case constNil:
+			p.Constants[i] = LNil
+		case constFalse:
+			p.Constants[i] = LFalse
+		case constTrue:
+			p.Constants[i] = LTrue
+		case constNumber:
+			p.Constants[i] = LNumber(math.Float64frombits(binary.BigEndian.Uint64(d.next(8, "number"))))
+		case constString:
+			strs[i] = d.string("string")
+			p.Constants[i] = LString(strs[i])
+		default:
+			d.fail("constant")
+		}
+	}
+	getField(p, stringConstants.Name).Set(reflect.ValueOf(strs))
+	p.FunctionPrototypes = make([]*FunctionProto, d.size(1, "prototypes"))
+	for i := range p.FunctionPrototypes {
+		p.FunctionPrototypes[i] = d.proto()
+	}
+	d.verify(p)
+	p.DbgSourcePositions = make([]int, d.size(1, "source positions"))
+	for i := range p.DbgSourcePositions {
+		p.DbgSourcePositions[i] = d.int("source position")
+	}
+	p.DbgLocals = make([]*DbgLocalInfo, d.size(3, "locals"))
+	for i := range p.DbgLocals {
+		p.DbgLocals[i] = &DbgLocalInfo{Name: d.string("local"), StartPc: d.int("local"), EndPc: d.int("local")}
+	}
+	p.DbgCalls = make([]DbgCall, d.size(2, "calls"))
+	for i := range p.DbgCalls {
+		p.DbgCalls[i] = DbgCall{Name: d.string("call"), Pc: d.int("call")}
+	}
+	p.DbgUpvalues = make([]string, d.size(1, "upvalues"))
+	for i := range p.DbgUpvalues {
+		p.DbgUpvalues[i] = d.string("upvalue")
+	}
+	return p
+}
+
+// verify the indexes of constants, prototypes, upvalues and jumps in code, which are not checked by the VM
+func (d *chunkDecoder) verify(p *FunctionProto) {
+	n := len(p.Code)
+	if n == 0 || int(p.Code[n-1]>>26) != OP_RETURN {
+		d.fail("code end")
+	}
+	constant := func(i int) {
+		if i >= len(p.Constants) {
+			d.fail("constant index")
+		}
+	}
+	rk := func(i int) {
+		if i&rkBit != 0 {
+			constant(i &^ rkBit)
+		}
+	}
+	jump := func(pc, offset int) {
+		if t := pc + 1 + offset; t < 0 || t >= n {
+			d.fail("jump")
+		}
+	}
+	for pc := 0; pc < n; pc++ {
+		inst := p.Code[pc]
+		op, b, c, bx := int(inst>>26), int(inst&0x1ff), int(inst>>9)&0x1ff, int(inst&0x3ffff)
+		switch op {
+		case OP_LOADK, OP_GETGLOBAL, OP_SETGLOBAL:
+			constant(bx)
+		case OP_GETTABLE, OP_GETTABLEKS, OP_SELF:
+			rk(c)
+		case OP_SETTABLE, OP_SETTABLEKS, OP_ADD, OP_SUB, OP_MUL, OP_DIV, OP_MOD, OP_POW:
+			rk(b)
+			rk(c)
+		case OP_EQ, OP_LT, OP_LE:
+			rk(b)
+			rk(c)
+			jump(pc, 1)
+		case OP_LOADBOOL:
+			if c != 0 {
+				jump(pc, 1)
+			}
+		case OP_TEST, OP_TESTSET, OP_TFORLOOP:
+			jump(pc, 1)
+		case OP_GETUPVAL, OP_SETUPVAL:
+			if b >= int(p.NumUpvalues) {
+				d.fail("upvalue index")
+			}
+		case OP_JMP, OP_FORLOOP, OP_FORPREP:
+			jump(pc, bx-sbxBias)
+		case OP_MOVEN:
+			if pc+c >= n {
+				d.fail("code")
+			}
+		case OP_SETLIST:
+			if c == 0 && pc+1 >= n {
+				d.fail("code")
+			}
+		case OP_CLOSURE:
+			if bx >= len(p.FunctionPrototypes) {
+				d.fail("prototype index")
+			}
+			//the pseudo instructions of upvalues
+			for i := 0; i < int(p.FunctionPrototypes[bx].NumUpvalues); i++ {
+				pc++
+				if pc >= n {
+					d.fail("code")
+				}
+				switch int(p.Code[pc] >> 26) {
+				case OP_MOVE:
+				case OP_GETUPVAL:
+					if int(p.Code[pc]&0x1ff) >= int(p.NumUpvalues) {
+						d.fail("upvalue index")
+					}
+				default:
+					d.fail("closure upvalue")
+				}
+			}
+		default:
+			if op > OP_NOP {
+				d.fail("opcode")
+			}
+		}
+	}
+}
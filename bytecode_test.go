@@ -0,0 +1,86 @@
+package glu
+
+import (
+	"bytes"
+	"errors"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+)
+
+func TestBytecode(t *testing.T) {
+	p, err := CompileChunk(`
+		local prefix='hello '
+		greeting=function(name) return prefix..name end
+		local function fib(n) if n<2 then return n end return fib(n-1)+fib(n-2) end
+		return greeting('world'), fib(10), 1.5, nil
+	`, "bytecode.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = DumpChunk(b, p); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	q, err := LoadChunk(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.String() != p.String() {
+		t.Fatal("should same proto", q.String())
+	}
+	err = ExecuteChunk(q, 0, 3, nil, func(s *Vm) error {
+		if s.ToString(1) != "hello world" || s.ToInt(2) != 55 || s.Get(3) != LNumber(1.5) {
+			return errors.New("bad result")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := append([]byte(nil), data...)
+	version[5]++
+	if _, err = LoadChunk(bytes.NewReader(version)); !errors.Is(err, ErrBytecodeVersion) {
+		t.Fatal("should reject version", err)
+	}
+	for _, corrupt := range [][]byte{
+		nil,
+		[]byte("GLUA"),
+		data[:len(data)-1],
+		append(append([]byte(nil), data[:20]...), data[21:]...),
+	} {
+		if _, err = LoadChunk(bytes.NewReader(corrupt)); !errors.Is(err, ErrBytecodeCorrupt) {
+			t.Fatal("should reject corrupt", err)
+		}
+	}
+	patch := func(p *FunctionProto, op int, arg uint32) {
+		for i, c := range p.Code {
+			if int(c>>26) == op {
+				p.Code[i] = c&^0x3ffff | arg
+				return
+			}
+		}
+		t.Fatal("missing instruction", op)
+	}
+	for name, craft := range map[string]func(p *FunctionProto){
+		"constant":  func(p *FunctionProto) { patch(p, OP_LOADK, 1000) },
+		"prototype": func(p *FunctionProto) { patch(p, OP_CLOSURE, 50) },
+		"upvalue":   func(p *FunctionProto) { patch(p.FunctionPrototypes[0], OP_GETUPVAL, 100) },
+		"jump":      func(p *FunctionProto) { patch(p.FunctionPrototypes[1], OP_JMP, 0x3ffff) },
+		"end":       func(p *FunctionProto) { p.Code = p.Code[:len(p.Code)-1] },
+	} {
+		p, _ := CompileChunk(`
+			local prefix='hello '
+			greeting=function(name) return prefix..name end
+			local function fib(n) if n<2 then return n end return fib(n-1)+fib(n-2) end
+		`, "crafted.lua")
+		craft(p)
+		b.Reset()
+		if err = DumpChunk(b, p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = LoadChunk(b); !errors.Is(err, ErrBytecodeCorrupt) {
+			t.Fatal("should reject crafted", name, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package glu
+
+import (
+	"container/list"
+	"crypto/sha256"
+	. "github.com/yuin/gopher-lua"
+	"sync"
+	"sync/atomic"
+)
+
+// ChunkCacheSize the initial max entries of DefaultChunkCache, use ChunkCache.SetCapacity to change it
+const ChunkCacheSize = 256
+
+// defaultChunkCache holds the chunkCacheRef of DefaultChunkCache
+var defaultChunkCache atomic.Value
+
+// chunkCacheRef the value stored in defaultChunkCache, atomic.Value can not store nil
+type chunkCacheRef struct {
+	c *ChunkCache
+}
+
+func init() {
+	defaultChunkCache.Store(chunkCacheRef{NewChunkCache(ChunkCacheSize)})
+}
+
+// DefaultChunkCache the cache used by Compiled and ExecuteCode, nil if caching disabled
+func DefaultChunkCache() *ChunkCache {
+	return defaultChunkCache.Load().(chunkCacheRef).c
+}
+
+// SetDefaultChunkCache replace the cache used by Compiled and ExecuteCode, nil to disable caching
+func SetDefaultChunkCache(c *ChunkCache) {
+	defaultChunkCache.Store(chunkCacheRef{c})
+}
+
+// Compiled compile code with DefaultChunkCache, see ChunkCache.Compile
+func Compiled(code string, name string) (*FunctionProto, error) {
+	if c := DefaultChunkCache(); c != nil {
+		return c.Compile(code, name)
+	}
+	return CompileChunk(code, name)
+}
+
+// ChunkCache a bounded concurrency-safe LRU cache of FunctionProto compiled by CompileChunk,
+// keyed by hash of source code and chunk name
+type ChunkCache struct {
+	m       sync.Mutex
+	max     int
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+	hits    uint64
+	misses  uint64
+}
+
+// CacheStats statistics of ChunkCache
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int //current entries
+	Max    int //max entries
+}
+
+type chunkEntry struct {
+	key   [sha256.Size]byte
+	proto *FunctionProto
+}
+
+// NewChunkCache create ChunkCache hold at most max entries
+func NewChunkCache(max int) *ChunkCache {
+	if max <= 0 {
+		panic("max size of cache must greater than 0")
+	}
+	return &ChunkCache{max: max, entries: make(map[[sha256.Size]byte]*list.Element), lru: list.New()}
+}
+
+func chunkKey(code string, name string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(name + "\x00" + code))
+}
+
+// Compile fetch the cached FunctionProto or compile and cache it. Compile errors are not cached.
+func (c *ChunkCache) Compile(code string, name string) (*FunctionProto, error) {
+	key := chunkKey(code, name)
+	c.m.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.hits++
+		c.lru.MoveToFront(e)
+		c.m.Unlock()
+		return e.Value.(*chunkEntry).proto, nil
+	}
+	c.misses++
+	c.m.Unlock()
+	proto, err := CompileChunk(code, name)
+	if err != nil {
+		return nil, err
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if e, ok := c.entries[key]; ok { //compiled concurrently
+		return e.Value.(*chunkEntry).proto, nil
+	}
+	c.entries[key] = c.lru.PushFront(&chunkEntry{key, proto})
+	c.evict()
+	return proto, nil
+}
+
+// SetCapacity change the max entries, the least recently used entries over max are removed
+func (c *ChunkCache) SetCapacity(max int) {
+	if max <= 0 {
+		panic("max size of cache must greater than 0")
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.max = max
+	c.evict()
+}
+
+// evict remove the least recently used entries over max
+func (c *ChunkCache) evict() {
+	for c.lru.Len() > c.max {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*chunkEntry).key)
+	}
+}
+
+// Invalidate remove the cached code with chunk name, returns true if exists
+func (c *ChunkCache) Invalidate(code string, name string) bool {
+	key := chunkKey(code, name)
+	c.m.Lock()
+	defer c.m.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		return true
+	}
+	return false
+}
+
+// Purge remove all cached entries and reset statistics
+func (c *ChunkCache) Purge() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.entries = make(map[[sha256.Size]byte]*list.Element)
+	c.lru.Init()
+	c.hits, c.misses = 0, 0
+}
+
+// Stats the statistics of cache
+func (c *ChunkCache) Stats() CacheStats {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Size: c.lru.Len(), Max: c.max}
+}
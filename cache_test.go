@@ -0,0 +1,68 @@
+package glu
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestChunkCache(t *testing.T) {
+	c := NewChunkCache(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Compile(`return 1`, "a"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if s := c.Stats(); s.Hits+s.Misses != 8 || s.Size != 1 {
+		t.Fatal(s)
+	}
+	p1, _ := c.Compile(`return 1`, "a")
+	p2, _ := c.Compile(`return 1`, "a")
+	if p1 != p2 {
+		t.Fatal("should cached")
+	}
+	if _, err := c.Compile(`return +`, "a"); err == nil || c.Stats().Size != 1 {
+		t.Fatal("compile error should not cached")
+	}
+	_, _ = c.Compile(`return 2`, "a")
+	_, _ = c.Compile(`return 3`, "a")
+	if s := c.Stats(); s.Size != 2 || c.Invalidate(`return 1`, "a") {
+		t.Fatal("should evict least recently used", s)
+	}
+	if !c.Invalidate(`return 3`, "a") || c.Stats().Size != 1 {
+		t.Fatal("should invalidated")
+	}
+	_, _ = c.Compile(`return 4`, "a")
+	c.SetCapacity(1)
+	if s := c.Stats(); s.Size != 1 || s.Max != 1 || c.Invalidate(`return 2`, "a") {
+		t.Fatal("should shrink to capacity", s)
+	}
+	c.Purge()
+	if s := c.Stats(); s.Size != 0 || s.Hits != 0 {
+		t.Fatal(s)
+	}
+	before := DefaultChunkCache().Stats().Hits
+	for i := 0; i < 2; i++ {
+		if err := ExecuteCode(`assert(true)`, 0, 0, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if DefaultChunkCache().Stats().Hits <= before {
+		t.Fatal("ExecuteCode should use cache")
+	}
+	prev := DefaultChunkCache()
+	defer SetDefaultChunkCache(prev)
+	SetDefaultChunkCache(nil)
+	if _, err := Compiled(`return 1`, "a"); err != nil || DefaultChunkCache() != nil {
+		t.Fatal("should compile without cache", err)
+	}
+	SetDefaultChunkCache(c)
+	if _, err := Compiled(`return 1`, "a"); err != nil || c.Stats().Size != 1 {
+		t.Fatal("should use the replaced cache", err)
+	}
+}
@@ -0,0 +1,175 @@
+package glu
+
+import (
+	"context"
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// BindFunc create typed Go function F which calls the Lua function in the Vm.
+//
+// The arguments are packed by Pack, the results are converted by Unpack. A leading context.Context parameter is not
+// passed to Lua, it bounds the execution instead (the previous context of Vm is restored after the call). When the
+// last result of F is error, the failure is returned as Error (or DecodeError for mismatched results, ContextError for
+// aborted calls), otherwise it panics.
+//
+// **Note** the returned function must be called on the goroutine owning the Vm, see BindPoolFunc for concurrent calls.
+// The Vm is closed after a call aborted by context, as its state may be inconsistent, later calls return ErrVmClosed.
+func BindFunc[F any](vm *Vm, fn *LFunction) F {
+	b := newFuncBinding(reflect.TypeOf((*F)(nil)).Elem())
+	return reflect.MakeFunc(b.typ, func(args []reflect.Value) []reflect.Value {
+		if vm.IsClosed() {
+			return b.returns(nil, ErrVmClosed)
+		}
+		ctx, args := b.arguments(args)
+		values := make([]LValue, len(args))
+		for i, arg := range args {
+			values[i] = packReflect(vm.LState, arg)
+		}
+		if ctx != nil {
+			prev := vm.Context()
+			vm.SetContext(ctx)
+			defer func() {
+				if vm.IsClosed() {
+					return
+				}
+				if prev != nil {
+					vm.SetContext(prev)
+				} else {
+					vm.RemoveContext()
+				}
+			}()
+		}
+		vm.Push(fn)
+		for _, v := range values {
+			vm.Push(v)
+		}
+		if err := vm.PCall(len(values), len(b.results), nil); err != nil {
+			err = AsError(err)
+			if c := vm.Context(); c != nil && c.Err() != nil {
+				//the aborted state may be inconsistent, never reuse it
+				vm.Close()
+				err = &ContextError{Err: c.Err(), Cause: err}
+			}
+			return b.returns(nil, err)
+		}
+		return b.returns(b.pop(vm.LState))
+	}).Interface().(F)
+}
+
+// BindPoolFunc create typed Go function F which calls the Lua function in a Vm borrowed from the pool for each call,
+// the returned function is goroutine safe. See BindFunc for the conversions.
+//
+// The Lua function is recreated from its prototype in each borrowed Vm, so it must not have upvalues, and it sees the
+// globals of the borrowed Vm, not the globals defined by the script created it (such as other global functions).
+func BindPoolFunc[F any](pl *VmPool, fn *LFunction) F {
+	if !fn.IsG && fn.Proto.NumUpvalues > 0 {
+		panic(fmt.Errorf("function with upvalues can not be shared: %s", fn))
+	}
+	b := newFuncBinding(reflect.TypeOf((*F)(nil)).Elem())
+	return reflect.MakeFunc(b.typ, func(args []reflect.Value) []reflect.Value {
+		ctx, args := b.arguments(args)
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		var out []reflect.Value
+		err := pl.execute(ctx, func(s *Vm) (LValue, error) {
+			if fn.IsG {
+				return fn, nil
+			}
+			return s.NewFunctionFromProto(fn.Proto), nil
+		}, len(args), len(b.results), func(s *Vm) error {
+			for _, arg := range args {
+				s.Push(packReflect(s.LState, arg))
+			}
+			return nil
+		}, func(s *Vm) (err error) {
+			out, err = b.pop(s.LState)
+			return
+		})
+		return b.returns(out, err)
+	}).Interface().(F)
+}
+
+// funcBinding the signature of function created by BindFunc
+type funcBinding struct {
+	typ     reflect.Type
+	ctx     bool           //the first parameter is context.Context
+	err     bool           //the last result is error
+	results []reflect.Type //the results converted from Lua
+}
+
+func newFuncBinding(t reflect.Type) *funcBinding {
+	if t.Kind() != reflect.Func {
+		panic(fmt.Errorf("%s is not a function type", t))
+	}
+	b := &funcBinding{typ: t}
+	b.ctx = t.NumIn() > 0 && t.In(0) == contextType
+	n := t.NumOut()
+	b.err = n > 0 && t.Out(n-1) == errorType
+	if b.err {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		b.results = append(b.results, t.Out(i))
+	}
+	return b
+}
+
+// arguments split the context and flatten the variadic arguments
+func (b *funcBinding) arguments(args []reflect.Value) (ctx context.Context, r []reflect.Value) {
+	if b.ctx {
+		if !args[0].IsNil() {
+			ctx = args[0].Interface().(context.Context)
+		}
+		args = args[1:]
+	}
+	if !b.typ.IsVariadic() {
+		return ctx, args
+	}
+	last := args[len(args)-1]
+	r = append(r, args[:len(args)-1]...)
+	for i := 0; i < last.Len(); i++ {
+		r = append(r, last.Index(i))
+	}
+	return
+}
+
+// pop convert and pop the results on stack
+func (b *funcBinding) pop(s *LState) (out []reflect.Value, err error) {
+	n := len(b.results)
+	defer s.Pop(n)
+	d := decoder{visiting: make(map[*LTable]bool)}
+	for i, t := range b.results {
+		v := reflect.New(t).Elem()
+		if err = d.decode(s.Get(i-n), v, fmt.Sprintf("result #%d", i+1)); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return
+}
+
+// returns the results of F, panic with err if F has no error result
+func (b *funcBinding) returns(out []reflect.Value, err error) []reflect.Value {
+	if err != nil {
+		if !b.err {
+			panic(err)
+		}
+		out = out[:0]
+		for _, t := range b.results {
+			out = append(out, reflect.Zero(t))
+		}
+	}
+	if b.err {
+		ev := reflect.Zero(errorType)
+		if err != nil {
+			ev = reflect.ValueOf(&err).Elem()
+		}
+		out = append(out, ev)
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package glu
+
+import (
+	"context"
+	"errors"
+	. "github.com/yuin/gopher-lua"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBindFunc(t *testing.T) {
+	s := Get()
+	defer Put(s)
+	if err := s.DoString(`
+		function check(name,n) return #name==n, nil end
+		function join(sep,...) return table.concat({...},sep) end
+		function fail(msg) error(msg) end
+		function point(p) return {x=p.x*2,y=p.y*2} end
+	`); err != nil {
+		t.Fatal(err)
+	}
+	check := BindFunc[func(string, int) (bool, error)](s, s.GetGlobal("check").(*LFunction))
+	if ok, err := check("abc", 3); !ok || err != nil {
+		t.Fatal("should call lua", ok, err)
+	}
+	join := BindFunc[func(string, ...string) string](s, s.GetGlobal("join").(*LFunction))
+	if v := join(",", "a", "b"); v != "a,b" {
+		t.Fatal("should pass variadic", v)
+	}
+	fail := BindFunc[func(string) error](s, s.GetGlobal("fail").(*LFunction))
+	var e *Error
+	if err := fail("boom"); !errors.As(err, &e) || e.Message != "boom" {
+		t.Fatal("should return Error", err)
+	}
+	type point struct{ X, Y int }
+	double := BindFunc[func(point) (point, error)](s, s.GetGlobal("point").(*LFunction))
+	if p, err := double(point{1, 2}); err != nil || p != (point{2, 4}) {
+		t.Fatal("should convert struct", p, err)
+	}
+	wrong := BindFunc[func(string, int) (int, error)](s, s.GetGlobal("check").(*LFunction))
+	if _, err := wrong("a", 1); !errors.Is(err, ErrDecode) || !strings.HasPrefix(err.Error(), "result #1") {
+		t.Fatal("should report mismatched result", err)
+	}
+	cyclic := map[string]any{}
+	cyclic["self"] = cyclic
+	func() {
+		defer func() {
+			if e, ok := recover().(error); !ok || !errors.Is(e, ErrCyclicValue) {
+				t.Fatal("should panic on cyclic argument", e)
+			}
+		}()
+		BindFunc[func(map[string]any)](s, s.GetGlobal("point").(*LFunction))(cyclic)
+	}()
+	if s.GetTop() != 0 {
+		t.Fatal("should keep stack balanced on packing failure", s.GetTop())
+	}
+	outer, stop := context.WithCancel(context.Background())
+	defer stop()
+	s.SetContext(outer)
+	checkCtx := BindFunc[func(context.Context, string, int) (bool, error)](s, s.GetGlobal("check").(*LFunction))
+	if ok, err := checkCtx(context.Background(), "a", 1); !ok || err != nil || s.Context() != outer {
+		t.Fatal("should restore previous context", ok, err)
+	}
+	s.RemoveContext()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("should panic without error result")
+			}
+		}()
+		BindFunc[func(string)](s, s.GetGlobal("fail").(*LFunction))("boom")
+	}()
+	p := MustNewPool(PoolConfig{})
+	v := p.Get()
+	defer p.Put(v)
+	if err := v.DoString(`function loop() while true do end end`); err != nil {
+		t.Fatal(err)
+	}
+	loop := BindFunc[func(context.Context) error](v, v.GetGlobal("loop").(*LFunction))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := loop(ctx); !errors.Is(err, ErrTimeout) {
+		t.Fatal("should abort by context", err)
+	}
+	if err := loop(context.Background()); !errors.Is(err, ErrVmClosed) {
+		t.Fatal("should not reuse aborted vm", err)
+	}
+}
+
+func TestBindPoolFunc(t *testing.T) {
+	s := Get()
+	defer Put(s)
+	if err := s.DoString(`
+		function greet(name) return string.format('hi %s',name) end
+		local n=0
+		function count() n=n+1 return n end
+	`); err != nil {
+		t.Fatal(err)
+	}
+	greet := BindPoolFunc[func(context.Context, string) (string, error)](defaultPool(), s.GetGlobal("greet").(*LFunction))
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := greet(context.Background(), "a"); err != nil || v != "hi a" {
+				t.Error("should call in pooled vm", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("should reject upvalues")
+			}
+		}()
+		BindPoolFunc[func() int](defaultPool(), s.GetGlobal("count").(*LFunction))
+	}()
+}
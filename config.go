@@ -0,0 +1,80 @@
+package glu
+
+import (
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"time"
+)
+
+// PoolConfig the configuration of a VmPool created by NewPool
+type PoolConfig struct {
+	// PoolOptions the LState options and limits
+	PoolOptions
+	// Registry the source of modules, nil for DefaultRegistry
+	Registry *Registry
+	// Modules names of modules to preload with their dependencies, nil for all modules of Registry.
+	// The Vms preload selected modules do not reload changes of Registry.
+	Modules []string
+	// Manual not preload any module of Registry, only the BaseMod
+	Manual bool
+	// Isolation the level of pollution detection and restore, default IsolationShallow
+	Isolation Isolation
+	// Sandbox the policy applied to each Vm, nil for not sandboxed
+	Sandbox *Sandbox
+	// InitialSize the initial capacity of idle Vms
+	InitialSize int
+	// WarmUp the count of Vms created by NewPool
+	WarmUp int
+	// Constructor custom constructor of LState, the default is NewState with Options
+	Constructor func(o Options) *LState
+}
+
+// NewPool create VmPool with configuration, the pools are independent of package variables Option, Auto and InitialSize.
+//
+// Returns ErrNotExists if any of Modules not registered in Registry.
+func NewPool(c PoolConfig) (*VmPool, error) {
+	if c.Registry == nil {
+		c.Registry = DefaultRegistry
+	}
+	for _, name := range c.Modules {
+		if !c.Registry.Has(name) {
+			return nil, fmt.Errorf("%w: %s", ErrNotExists, name)
+		}
+	}
+	if c.InitialSize < c.WarmUp {
+		c.InitialSize = c.WarmUp
+	}
+	pl := &VmPool{saved: make([]*Vm, 0, c.InitialSize), config: c}
+	if c.MaxSize > 0 {
+		pl.slots = make(chan struct{}, c.MaxSize)
+	}
+	if c.IdleTimeout > 0 {
+		interval := c.IdleTimeout / 2
+		if interval <= 0 {
+			interval = c.IdleTimeout
+		}
+		pl.done = make(chan struct{})
+		go pl.evictLoop(interval, pl.done)
+	}
+	for i := 0; i < c.WarmUp; i++ {
+		v := pl.new()
+		v.idle = time.Now()
+		pl.saved = append(pl.saved, v)
+		pl.stats.Created++
+	}
+	return pl, nil
+}
+
+// MustNewPool create VmPool by NewPool, panics on error
+func MustNewPool(c PoolConfig) *VmPool {
+	pl, err := NewPool(c)
+	if err != nil {
+		panic(err)
+	}
+	return pl
+}
+
+// Config the configuration of this pool
+func (pl *VmPool) Config() PoolConfig {
+	return pl.config
+}
@@ -0,0 +1,64 @@
+package glu
+
+import (
+	"errors"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+)
+
+func TestNewPool(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(
+		NewModule("base", ``, true),
+		NewModule("feature", ``, true).DependsOn("base"),
+		NewModule("other", ``, true),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPool(PoolConfig{Registry: r, Modules: []string{"nope"}}); !errors.Is(err, ErrNotExists) {
+		t.Fatalf("should reject unknown module: %v", err)
+	}
+	constructed := 0
+	p := MustNewPool(PoolConfig{
+		PoolOptions: PoolOptions{Options: Options{CallStackSize: 128}},
+		Registry:    r,
+		Modules:     []string{"feature"},
+		Sandbox:     &Sandbox{Deny: []string{"dofile"}},
+		WarmUp:      2,
+		Constructor: func(o Options) *LState {
+			constructed++
+			if o.CallStackSize != 128 {
+				t.Fatal("should pass options")
+			}
+			return NewState(o)
+		},
+	})
+	defer p.Shutdown()
+	if s := p.Stats(); s.Created != 2 || s.Idle != 2 || constructed != 2 {
+		t.Fatalf("should warm up %+v", s)
+	}
+	v := p.Get()
+	defer p.Put(v)
+	if err := v.DoString(`
+		assert(require('feature') and require('base'))
+		assert(not pcall(require,'other'))
+		assert(not pcall(dofile,'x.lua'))
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if Option.CallStackSize == 128 {
+		t.Fatal("should not change package variable")
+	}
+}
+
+func TestZeroPool(t *testing.T) {
+	p := new(VmPool)
+	if p.Registry() != DefaultRegistry {
+		t.Fatal("should use DefaultRegistry")
+	}
+	v := p.Get()
+	defer p.Put(v)
+	if err := v.DoString(`assert(help)`); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+package glu
+
+import (
+	"context"
+	"errors"
+	. "github.com/yuin/gopher-lua"
+)
+
+var (
+	// ErrTimeout execution aborted by deadline of context
+	ErrTimeout = errors.New("execution timeout")
+	// ErrCanceled execution aborted by cancel of context
+	ErrCanceled = errors.New("execution canceled")
+)
+
+// ContextError the error of execution aborted by context.
+//
+// errors.Is matches ErrTimeout or ErrCanceled, and the context error (context.DeadlineExceeded or context.Canceled).
+type ContextError struct {
+	Err   error //the error of context
+	Cause error //the error raised in LState
+}
+
+func (e *ContextError) Error() string {
+	if e.Err == context.DeadlineExceeded {
+		return ErrTimeout.Error() + ": " + e.Cause.Error()
+	}
+	return ErrCanceled.Error() + ": " + e.Cause.Error()
+}
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+func (e *ContextError) Is(target error) bool {
+	switch target {
+	case ErrTimeout:
+		return e.Err == context.DeadlineExceeded
+	case ErrCanceled:
+		return e.Err != context.DeadlineExceeded
+	}
+	return false
+}
+
+// ExecuteChunkWithContext execute pre complied FunctionProto in default pool, see VmPool.ExecuteChunk
+func ExecuteChunkWithContext(ctx context.Context, code *FunctionProto, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return defaultPool().ExecuteChunk(ctx, code, argN, retN, before, after, opts...)
+}
+
+// ExecuteFunctionWithContext execute function in default pool, see VmPool.ExecuteChunk
+func ExecuteFunctionWithContext(ctx context.Context, fn *LFunction, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return defaultPool().ExecuteFunction(ctx, fn, argN, retN, before, after, opts...)
+}
+
+// ExecuteCodeWithContext run code in default pool, see VmPool.ExecuteChunk
+func ExecuteCodeWithContext(ctx context.Context, code string, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return defaultPool().ExecuteCode(ctx, code, argN, retN, before, after, opts...)
+}
+
+// ExecuteChunk execute pre complied FunctionProto, abort when ctx is done with a ContextError,
+// or when exceeded limits of PoolOptions with a LimitError. The errors raised in Lua are returned as Error.
+//
+// The Vm of an aborted execution is closed instead of returned to the pool.
+func (pl *VmPool) ExecuteChunk(ctx context.Context, code *FunctionProto, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return pl.execute(ctx, func(s *Vm) (LValue, error) {
+		return s.NewFunctionFromProto(code), nil
+	}, argN, retN, before, after, opts...)
+}
+
+// ExecuteFunction execute function, see VmPool.ExecuteChunk
+func (pl *VmPool) ExecuteFunction(ctx context.Context, fn *LFunction, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return pl.execute(ctx, func(s *Vm) (LValue, error) {
+		return fn, nil
+	}, argN, retN, before, after, opts...)
+}
+
+// ExecuteCode run code, the compiled chunk is cached by DefaultChunkCache, see VmPool.ExecuteChunk
+func (pl *VmPool) ExecuteCode(ctx context.Context, code string, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return pl.execute(ctx, func(s *Vm) (LValue, error) {
+		proto, err := Compiled(code, "<string>")
+		if err != nil {
+			return nil, err
+		}
+		return s.NewFunctionFromProto(proto), nil
+	}, argN, retN, before, after, opts...)
+}
+
+// execute the function from load in a pooled Vm
+func (pl *VmPool) execute(ctx context.Context, load func(s *Vm) (LValue, error), argN, retN int, before Operator, after Operator, opts ...ExecuteOption) (err error) {
+	parent := ctx
+	if t := pl.config.Timeout; t > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+	s, err := pl.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	bound := ctx.Done() != nil
+	if bound {
+		s.SetContext(ctx)
+	}
+	discard := false
+	defer func() {
+		if bound {
+			s.RemoveContext()
+		}
+		if discard {
+			//the aborted state may be inconsistent, never reuse it
+			s.Close()
+		}
+		pl.Put(s)
+	}()
+	fn, err := load(s)
+	if err != nil {
+		return err
+	}
+	if hasOption(opts, FreshEnv) {
+		fn = s.withEnv(fn)
+	}
+	s.Push(fn)
+	if before != nil {
+		if err = before(s); err != nil {
+			return err
+		}
+	}
+	limit := ""
+	s.Insert(guarded(s.LState, &limit), s.GetTop()-argN)
+	if err = s.PCall(argN+1, retN, nil); err != nil {
+		err = AsError(err)
+		if bound && ctx.Err() != nil {
+			discard = true
+			err = &ContextError{Err: ctx.Err(), Cause: err}
+			if parent.Err() == nil {
+				err = &LimitError{Limit: LimitTimeout, Cause: err}
+			}
+		} else if limit != "" {
+			discard = true
+			err = &LimitError{Limit: limit, Cause: err}
+		}
+		return err
+	}
+	if after != nil {
+		return after(s)
+	}
+	return nil
+}
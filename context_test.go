@@ -0,0 +1,34 @@
+package glu
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecuteWithContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := ExecuteCodeWithContext(ctx, `while true do end`, 0, 0, nil, nil)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCanceled) {
+		t.Fatal("should timeout", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	err = ExecuteCodeWithContext(ctx, `local i=0 while true do i=i+1 end`, 0, 0, nil, nil)
+	if !errors.Is(err, ErrCanceled) || errors.Is(err, ErrTimeout) {
+		t.Fatal("should canceled", err)
+	}
+	if err = ExecuteCodeWithContext(context.Background(), `error('boom')`, 0, 0, nil, nil); err == nil || errors.Is(err, ErrCanceled) {
+		t.Fatal("should be a plain error", err)
+	}
+	v := Get()
+	defer Put(v)
+	if v.IsClosed() || v.Context() != nil {
+		t.Fatal("pooled vm should be clean")
+	}
+}
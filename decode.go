@@ -0,0 +1,351 @@
+package glu
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDecode the failure of Decode, matched by errors.Is on DecodeError
+var ErrDecode = errors.New("decode")
+
+// DecodeError the failure of Decode at the path of value, like `config.servers[2].port: expected number`
+type DecodeError struct {
+	Path    string //the path of value, empty for the root
+	Message string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// structField the field of struct mapped by tag `lua:"name,omitempty"`
+type structField struct {
+	index     []int
+	name      string
+	omitempty bool
+}
+
+var structFieldCache sync.Map // reflect.Type => []structField
+
+// structFields the mapped fields of struct type, embedded structs without tag name are flattened.
+//
+// The field name is the tag name or lower camel case of Go name, same as BindType.
+func structFields(t reflect.Type) []structField {
+	if v, ok := structFieldCache.Load(t); ok {
+		return v.([]structField)
+	}
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, opts, _ := strings.Cut(f.Tag.Get(BindTagName), ",")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			for _, sf := range structFields(f.Type) {
+				sf.index = append([]int{i}, sf.index...)
+				fields = append(fields, sf)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = luaName(f.Name)
+		}
+		fields = append(fields, structField{index: f.Index, name: tag, omitempty: contains(strings.Split(opts, ","), "omitempty")})
+	}
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+// Decode map the LTable onto T, which may be a struct, slice, array, map or pointer of them.
+//
+// Struct fields are mapped by tag `lua:"name,omitempty"` (see BindType for default names), a missing value is an error
+// unless the field is tagged with omitempty or is a pointer, interface, slice or map.
+//
+// Numbers are converted from numeric strings, strings from numbers and []byte from string.
+// The trailing elements of array are zero when the table is shorter.
+// Returns DecodeError with the path of the failed value.
+func Decode[T any](tbl *LTable) (v T, err error) {
+	if tbl == nil {
+		return v, &DecodeError{Message: "expected table"}
+	}
+	d := decoder{visiting: make(map[*LTable]bool)}
+	err = d.decode(tbl, reflect.ValueOf(&v).Elem(), "")
+	return
+}
+
+// Unpack convert LValue to T, the inverse of Pack. The conversion is the same as Decode,
+// time.Time is converted from seconds since epoch (see Pack) in UTC or RFC3339 string.
+func Unpack[T any](v LValue) (r T, err error) {
+	d := decoder{visiting: make(map[*LTable]bool)}
+	err = d.decode(v, reflect.ValueOf(&r).Elem(), "")
+	return
+}
+
+// CheckStruct decode the table at n into T by Decode. Otherwise, an argument error raised.
+func CheckStruct[T any](s *LState, n int) T {
+	v, err := Decode[T](s.CheckTable(n))
+	if err != nil {
+		s.ArgError(n, err.Error())
+	}
+	return v
+}
+
+type decoder struct {
+	visiting map[*LTable]bool
+}
+
+func (d decoder) fail(path string, format string, args ...any) error {
+	return &DecodeError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// expected the Lua type name expected for the Go type
+func expected(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return "table"
+	}
+	return typeName(t)
+}
+
+func (d decoder) decode(v LValue, rv reflect.Value, path string) error {
+	t := rv.Type()
+	if v == LNil {
+		if nullable(t) {
+			rv.Set(reflect.Zero(t))
+			return nil
+		}
+		return d.fail(path, "expected %s", expected(t))
+	}
+	if x := reflect.ValueOf(v); x.Type().AssignableTo(t) && t.Kind() != reflect.Interface || t == lValueType {
+		rv.Set(x)
+		return nil
+	}
+	if ud, ok := v.(*LUserData); ok && ud.Value != nil {
+		if x := reflect.ValueOf(ud.Value); x.Type().AssignableTo(t) {
+			rv.Set(x)
+			return nil
+		}
+	}
+	if t == timeType {
+		switch x := v.(type) {
+		case LNumber:
+			rv.Set(reflect.ValueOf(time.UnixMicro(int64(math.Round(float64(x) * 1e6))).UTC()))
+			return nil
+		case LString:
+			tm, err := time.Parse(time.RFC3339Nano, string(x))
+			if err != nil {
+				return d.fail(path, "expected timestamp")
+			}
+			rv.Set(reflect.ValueOf(tm))
+			return nil
+		}
+		return d.fail(path, "expected timestamp")
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		p := reflect.New(t.Elem())
+		if err := d.decode(v, p.Elem(), path); err != nil {
+			return err
+		}
+		rv.Set(p)
+		return nil
+	case reflect.Interface:
+		if x, ok := fromLValue(v, t); ok {
+			rv.Set(x)
+			return nil
+		}
+	case reflect.String:
+		switch v.Type() {
+		case LTString, LTNumber:
+			rv.SetString(v.String())
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := v.(LBool); ok {
+			rv.SetBool(bool(b))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := d.number(v); ok {
+			if f != math.Trunc(f) {
+				return d.fail(path, "expected integer")
+			}
+			if f < math.MinInt64 || f >= math.MaxInt64 || rv.OverflowInt(int64(f)) {
+				return d.fail(path, "number overflow of %s", t)
+			}
+			rv.SetInt(int64(f))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := d.number(v); ok {
+			if f != math.Trunc(f) {
+				return d.fail(path, "expected integer")
+			}
+			if f < 0 || f >= math.MaxUint64 || rv.OverflowUint(uint64(f)) {
+				return d.fail(path, "number overflow of %s", t)
+			}
+			rv.SetUint(uint64(f))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := d.number(v); ok {
+			if rv.OverflowFloat(f) {
+				return d.fail(path, "number overflow of %s", t)
+			}
+			rv.SetFloat(f)
+			return nil
+		}
+	case reflect.Slice:
+		if s, ok := v.(LString); ok && t.Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes([]byte(s))
+			return nil
+		}
+		if tb, ok := v.(*LTable); ok {
+			return d.table(tb, path, func() error {
+				n := tb.Len()
+				x := reflect.MakeSlice(t, n, n)
+				for i := 0; i < n; i++ {
+					if err := d.decode(tb.RawGetInt(i+1), x.Index(i), index(path, i+1)); err != nil {
+						return err
+					}
+				}
+				rv.Set(x)
+				return nil
+			})
+		}
+	case reflect.Array:
+		if tb, ok := v.(*LTable); ok {
+			return d.table(tb, path, func() error {
+				if tb.Len() > rv.Len() {
+					return d.fail(path, "expected at most %d elements", rv.Len())
+				}
+				n := tb.Len()
+				for i := 0; i < rv.Len(); i++ {
+					if i >= n {
+						rv.Index(i).Set(reflect.Zero(t.Elem()))
+						continue
+					}
+					if err := d.decode(tb.RawGetInt(i+1), rv.Index(i), index(path, i+1)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	case reflect.Map:
+		if tb, ok := v.(*LTable); ok {
+			return d.table(tb, path, func() (err error) {
+				x := reflect.MakeMap(t)
+				tb.ForEach(func(k LValue, val LValue) {
+					if err != nil {
+						return
+					}
+					p := member(path, k)
+					kv := reflect.New(t.Key()).Elem()
+					if err = d.decode(k, kv, p); err != nil {
+						return
+					}
+					ev := reflect.New(t.Elem()).Elem()
+					if err = d.decode(val, ev, p); err != nil {
+						return
+					}
+					x.SetMapIndex(kv, ev)
+				})
+				if err == nil {
+					rv.Set(x)
+				}
+				return
+			})
+		}
+	case reflect.Struct:
+		if tb, ok := v.(*LTable); ok {
+			return d.table(tb, path, func() error {
+				for _, f := range structFields(t) {
+					p := member(path, LString(f.name))
+					val := tb.RawGetString(f.name)
+					if val == LNil && f.omitempty {
+						continue
+					}
+					if val == LNil && !nullable(t.FieldByIndex(f.index).Type) {
+						return d.fail(p, "missing required field")
+					}
+					if err := d.decode(val, rv.FieldByIndex(f.index), p); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return d.fail(path, "expected %s", expected(t))
+}
+
+// table decode the table by act, cyclic reference is an error
+func (d decoder) table(tb *LTable, path string, act func() error) error {
+	if d.visiting[tb] {
+		return d.fail(path, "cyclic table")
+	}
+	d.visiting[tb] = true
+	defer delete(d.visiting, tb)
+	return act()
+}
+
+// number the number or numeric string
+func (d decoder) number(v LValue) (float64, bool) {
+	switch x := v.(type) {
+	case LNumber:
+		return float64(x), true
+	case LString:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(x)), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// nullable the types accept nil
+func nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+func index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+func member(path string, k LValue) string {
+	if s, ok := k.(LString); ok {
+		if path == "" {
+			return string(s)
+		}
+		return path + "." + string(s)
+	}
+	return path + "[" + k.String() + "]"
+}
@@ -0,0 +1,101 @@
+package glu
+
+import (
+	"errors"
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+	"testing"
+)
+
+type decodeServer struct {
+	Host string
+	Port uint16
+	Tags []string `lua:"tags,omitempty"`
+}
+type decodeBase struct {
+	Name string
+}
+type decodeConfig struct {
+	decodeBase
+	Servers []decodeServer `lua:"servers"`
+	Limits  map[string]int
+	Ratio   float64 `lua:"ratio,omitempty"`
+	Debug   *bool
+	Key     []byte `lua:"key,omitempty"`
+	Skip    string `lua:"-"`
+	Extra   any    `lua:"extra,omitempty"`
+}
+
+type decodeNode struct {
+	Name string
+	Next *decodeNode `lua:"next,omitempty"`
+}
+
+func init() {
+	fn.Panic(Register(NewModule("decodeTest", `decode tables`, true).
+		AddFunc("count", `(config table)int`, func(s *LState) int {
+			c := CheckStruct[*decodeConfig](s, 1)
+			s.Push(LNumber(len(c.Servers)))
+			return 1
+		})))
+}
+
+func TestDecode(t *testing.T) {
+	s := Get()
+	defer Put(s)
+	decode := func(code string) (*decodeConfig, error) {
+		if err := s.DoString(`return ` + code); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Pop(1)
+		return Decode[*decodeConfig](s.CheckTable(-1))
+	}
+	c, err := decode(`{name='a',servers={{host='h1',port=80},{host='h2',port='8080',tags={'x',1}}},limits={cpu=2},debug=true,key='k',extra={1}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "a" || len(c.Servers) != 2 || c.Servers[1].Port != 8080 || !reflect.DeepEqual(c.Servers[1].Tags, []string{"x", "1"}) ||
+		c.Limits["cpu"] != 2 || c.Debug == nil || !*c.Debug || string(c.Key) != "k" || c.Extra == nil {
+		t.Fatalf("bad decode %#v", c)
+	}
+	for code, msg := range map[string]string{
+		`{name='a',servers={{host='h',port=1},{host='h',port='x'}}}`: "servers[2].port: expected number",
+		`{name='a',servers={{host='h',port=70000}}}`:                 "servers[1].port: number overflow of uint16",
+		`{name='a',servers={{host='h',port=1.5}}}`:                   "servers[1].port: expected integer",
+		`{name='a',servers={{port=1}}}`:                              "servers[1].host: missing required field",
+		`{name='a',limits={cpu=true}}`:                               "limits.cpu: expected number",
+		`{name={}}`:                                                  "name: expected string",
+	} {
+		_, err = decode(code)
+		var de *DecodeError
+		if !errors.Is(err, ErrDecode) || !errors.As(err, &de) || err.Error() != msg {
+			t.Fatalf("%s should fail with %s: %v", code, msg, err)
+		}
+	}
+	if err = s.DoString(`return {1,2}`); err != nil {
+		t.Fatal(err)
+	}
+	if a, err := Decode[[3]int](s.CheckTable(-1)); err != nil || a != [3]int{1, 2, 0} {
+		t.Fatal("should zero trailing array elements", a, err)
+	}
+	s.Pop(1)
+	if _, err = Decode[decodeNode](nil); !errors.Is(err, ErrDecode) {
+		t.Fatal("should reject nil table", err)
+	}
+	if err = s.DoString(`local t={name='a'} t.next=t return t`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decode[decodeNode](s.CheckTable(-1)); err == nil || err.Error() != "next: cyclic table" {
+		t.Fatal("should detect cycle", err)
+	}
+	s.Pop(1)
+	if err = ExecuteCode(`
+		local m=require('decodeTest')
+		assert(m.count({name='a',servers={{host='h',port=1}}})==1)
+		local ok,err=pcall(m.count,{name='a',servers={{host='h'}}})
+		assert(not ok and string.find(err,'servers[1].port: missing required field',1,true),err)
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
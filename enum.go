@@ -0,0 +1,152 @@
+package glu
+
+import (
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Enum a read-only group of constants, which supports forward lookup `Enum.Name`, reverse lookup `Enum[value]`
+// and `Enum.members()` for a table of names and values to iterate by pairs (unless a member named `members`).
+type Enum struct {
+	Name    string
+	Help    string
+	members map[string]LValue
+	keys    []string //member names ordered by value
+}
+
+// NewEnum create Enum, the member values must be unique numbers, strings or booleans,
+// a string value must not be the name of another member.
+func NewEnum(name string, help string, members map[string]any) *Enum {
+	e := &Enum{Name: name, Help: help, members: make(map[string]LValue, len(members))}
+	values := make(map[LValue]string, len(members))
+	for k, v := range members {
+		var lv LValue
+		switch x := v.(type) {
+		case string:
+			lv = LString(x)
+		case bool:
+			lv = LBool(x)
+		default:
+			rv := reflect.ValueOf(v)
+			switch {
+			case rv.CanInt():
+				lv = LNumber(rv.Int())
+			case rv.CanUint():
+				lv = LNumber(rv.Uint())
+			case rv.CanFloat():
+				lv = LNumber(rv.Float())
+			default:
+				panic(fmt.Errorf("enum %s.%s: unsupported value %#v", name, k, v))
+			}
+		}
+		if o, ok := values[lv]; ok {
+			panic(fmt.Errorf("enum %s.%s: duplicate value with %s", name, k, o))
+		}
+		values[lv] = k
+		e.members[k] = lv
+		e.keys = append(e.keys, k)
+	}
+	for v, k := range values {
+		if _, ok := e.members[v.String()]; ok && v.Type() == LTString && v.String() != k {
+			panic(fmt.Errorf("enum %s.%s: value conflicts with member %s", name, k, v.String()))
+		}
+	}
+	sort.Slice(e.keys, func(i, j int) bool {
+		a, b := e.members[e.keys[i]], e.members[e.keys[j]]
+		if a.Type() == LTNumber && b.Type() == LTNumber && a != b {
+			return a.(LNumber) < b.(LNumber)
+		}
+		return e.keys[i] < e.keys[j]
+	})
+	return e
+}
+
+// Member the value of member, panic if not exists
+func (e *Enum) Member(name string) LValue {
+	if v, ok := e.members[name]; ok {
+		return v
+	}
+	panic(fmt.Errorf("enum %s have no member %s", e.Name, name))
+}
+
+// Lookup the member name of value
+func (e *Enum) Lookup(v LValue) (string, bool) {
+	for _, k := range e.keys {
+		if e.members[k] == v {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// String the signature style help: `enum{A=1,B=2} 	 help`
+func (e *Enum) String() string {
+	b := new(strings.Builder)
+	b.WriteString("enum{")
+	for i, k := range e.keys {
+		if i > 0 {
+			b.WriteRune(',')
+		}
+		v := e.members[k]
+		if v.Type() == LTString {
+			fmt.Fprintf(b, "%s=%q", k, v.String())
+		} else {
+			fmt.Fprintf(b, "%s=%s", k, v.String())
+		}
+	}
+	b.WriteRune('}')
+	if e.Help != "" {
+		b.WriteString(" \t ")
+		b.WriteString(e.Help)
+	}
+	return b.String()
+}
+
+// Table create the read-only Lua table of Enum
+func (e *Enum) Table(l *LState) LValue {
+	forward := l.NewTable()
+	reverse := l.NewTable()
+	for _, k := range e.keys {
+		forward.RawSetString(k, e.members[k])
+		reverse.RawSet(e.members[k], LString(k))
+	}
+	members := l.NewFunction(func(s *LState) int {
+		m := s.NewTable()
+		forward.ForEach(m.RawSet)
+		s.Push(m)
+		return 1
+	})
+	t := l.NewTable()
+	mt := l.NewTable()
+	mt.RawSetString("__index", l.NewFunction(func(s *LState) int {
+		k := s.Get(2)
+		if v := forward.RawGet(k); v != LNil {
+			s.Push(v)
+		} else if v = reverse.RawGet(k); v != LNil {
+			s.Push(v)
+		} else if k == LString("members") {
+			s.Push(members)
+		} else {
+			s.Push(LNil)
+		}
+		return 1
+	}))
+	mt.RawSetString("__newindex", l.NewFunction(func(s *LState) int {
+		s.RaiseError("enum %s is read only, members: %s", e.Name, strings.Join(e.keys, ","))
+		return 0
+	}))
+	mt.RawSetString("__len", l.NewFunction(func(s *LState) int {
+		s.Push(LNumber(len(e.keys)))
+		return 1
+	}))
+	mt.RawSetString("__tostring", l.NewFunction(func(s *LState) int {
+		s.Push(LString(e.String()))
+		return 1
+	}))
+	mt.RawSetString("__metatable", LString("enum "+e.Name))
+	l.SetMetatable(t, mt)
+	return t
+}
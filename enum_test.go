@@ -0,0 +1,67 @@
+package glu
+
+import (
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+)
+
+func init() {
+	fn.Panic(Register(NewModule("enumTest", `enum test module`, true).
+		AddEnum("Level", `log level`, map[string]any{
+			"Debug": 0,
+			"Info":  1,
+			"Warn":  uint8(2),
+			"Error": 3.0,
+		}).
+		AddEnum("Mode", `open mode`, map[string]any{
+			"Read":  "r",
+			"Write": "w",
+		})))
+}
+
+func TestEnum(t *testing.T) {
+	e := NewEnum("Level", ``, map[string]any{"B": 2, "A": 1})
+	if e.String() != "enum{A=1,B=2}" {
+		t.Fatal(e.String())
+	}
+	if n, ok := e.Lookup(LNumber(2)); !ok || n != "B" {
+		t.Fatal("reverse lookup failed")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("should panic on duplicate value")
+			}
+		}()
+		NewEnum("Bad", ``, map[string]any{"A": 1, "B": 1})
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("should panic on value conflicts with member name")
+			}
+		}()
+		NewEnum("Bad", ``, map[string]any{"Read": "r", "r": "x"})
+	}()
+	err := ExecuteCode(`
+		local m=require('enumTest')
+		assert(m.Level.Info==1 and m.Level[3]=='Error')
+		assert(m.Mode.Read=='r' and m.Mode.w=='Write')
+		assert(#m.Level==4)
+		assert(m.Level.Fatal==nil)
+		local ok,err=pcall(function() m.Level.Fatal=4 end)
+		assert(not ok and string.find(err,'read only'))
+		assert(not pcall(setmetatable,m.Level,{}))
+		local n=0
+		for k,v in pairs(m.Level.members()) do
+			assert(m.Level[k]==v and m.Level[v]==k)
+			n=n+1
+		end
+		assert(n==4)
+		print(m.help('Level'))
+	`, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
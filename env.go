@@ -0,0 +1,49 @@
+package glu
+
+import (
+	. "github.com/yuin/gopher-lua"
+)
+
+// ExecuteOption the options of Execute functions
+type ExecuteOption uint8
+
+const (
+	// FreshEnv run with a copy-on-write environment created by Vm.NewEnv, the global writes never touch the shared globals.
+	//
+	// The shared globals are still reachable, such as `getfenv(0)` or modules, so the Vm is checked for pollution as usual.
+	FreshEnv ExecuteOption = 1 << iota
+)
+
+func hasOption(opts []ExecuteOption, o ExecuteOption) bool {
+	for _, x := range opts {
+		if x&o != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEnv create a copy-on-write environment: reads fall back to the shared globals, writes stay in the environment.
+//
+// The `_G` of the environment is itself. **Note** the tables in globals are still shared, such as `string`,
+// use Sandbox.ReadOnly or IsolationDeep to protect them.
+func (s *Vm) NewEnv() *LTable {
+	env := s.NewTable()
+	env.RawSetString("_G", env)
+	mt := s.NewTable()
+	mt.RawSetString("__index", s.G.Global)
+	mt.RawSetString("__metatable", LString("environment"))
+	s.SetMetatable(env, mt)
+	return env
+}
+
+// withEnv copy the Lua function with a new environment, the Go function is returned as is
+func (s *Vm) withEnv(fn LValue) LValue {
+	f, ok := fn.(*LFunction)
+	if !ok || f.IsG {
+		return fn
+	}
+	c := *f
+	c.Env = s.NewEnv()
+	return &c
+}
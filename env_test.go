@@ -0,0 +1,59 @@
+package glu
+
+import (
+	"context"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+)
+
+func TestFreshEnv(t *testing.T) {
+	p := MustNewPool(PoolConfig{})
+	defer p.Shutdown()
+	ctx := context.Background()
+	if err := p.ExecuteCode(ctx, `
+		leak=1
+		_G.leak2=2
+		assert(leak==1 and leak2==2 and type(print)=='function')
+		local function f() return leak end
+		assert(f()==1)
+	`, 0, 0, nil, nil, FreshEnv); err != nil {
+		t.Fatal(err)
+	}
+	v := p.Get()
+	if v.GetGlobal("leak") != LNil || v.GetGlobal("leak2") != LNil {
+		t.Fatal("should not leak into globals")
+	}
+	if err := v.DoString(`function shared() x=(x or 0)+1 return x end`); err != nil {
+		t.Fatal(err)
+	}
+	fn := v.GetGlobal("shared").(*LFunction)
+	p.Put(v)
+	for i := 0; i < 2; i++ {
+		if err := p.ExecuteFunction(ctx, fn, 0, 1, nil, func(s *Vm) error {
+			if s.Get(-1) != LNumber(1) {
+				t.Fatal("each execution should have own environment", s.Get(-1))
+			}
+			return nil
+		}, FreshEnv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := p.Stats(); s.Polluted != 1 {
+		t.Fatalf("%+v", s)
+	}
+}
+
+func TestFreshEnvThreadGlobals(t *testing.T) {
+	p := MustNewPool(PoolConfig{})
+	defer p.Shutdown()
+	ctx := context.Background()
+	if err := p.ExecuteCode(ctx, `getfenv(0).X=1`, 0, 0, nil, nil, FreshEnv); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ExecuteCode(ctx, `assert(X==nil,'leaked through getfenv(0)')`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.Polluted != 1 {
+		t.Fatalf("should detect pollution through real globals %+v", s)
+	}
+}
@@ -0,0 +1,205 @@
+package glu
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errorKey the registry key of metatable of Lua error userdata
+const errorKey = "_ERROR"
+
+// Error the structured error of Lua execution, returned by Execute* and raised to Lua as error userdata by Throw.
+//
+// errors.Is and errors.As match the Cause.
+type Error struct {
+	Code      int    //the error code, 0 if not specified
+	Message   string //the message without position
+	Source    string //the source name of the chunk raised the error, empty if unknown
+	Line      int    //the line raised the error, 0 if unknown
+	Traceback string //the Lua stack traceback
+	Cause     error  //the Go error, nil if raised by Lua
+}
+
+// NewError create Error of the Go error with code
+func NewError(code int, cause error) *Error {
+	return &Error{Code: code, Message: cause.Error(), Cause: cause}
+}
+
+// Errorf create Error with code and formatted message
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	if e.Traceback != "" {
+		return e.text() + "\n" + e.Traceback
+	}
+	return e.text()
+}
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// text the message with position
+func (e *Error) text() string {
+	if e.Source == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Source, e.Line, e.Message)
+}
+
+var positionPattern = regexp.MustCompile(`(?s)^([^\n]+?):(\d+):(?: |$)(.*)$`)
+
+// locate fill Source and Line from the position prefix like `<string>:3: `, returns the message without prefix.
+// The prefix must be a frame of the traceback, a message like `dial tcp 127.0.0.1:5432: refused` is kept as is.
+func (e *Error) locate(msg, traceback string) string {
+	if m := positionPattern.FindStringSubmatch(msg); m != nil && strings.Contains(traceback, "\t"+m[1]+":"+m[2]+":") {
+		e.Source = m[1]
+		e.Line, _ = strconv.Atoi(m[2])
+		return m[3]
+	}
+	return msg
+}
+
+// Throw raise err to Lua as error userdata with position and traceback of current function, err is wrapped by NewError if not an Error.
+func Throw(s *LState, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = NewError(0, err)
+	} else if e != err {
+		//keep the outer message, without the traceback of inner
+		e = &Error{Code: e.Code, Message: strings.Replace(err.Error(), e.Error(), e.text(), 1), Cause: err}
+	} else {
+		c := *e
+		e = &c
+	}
+	if e.Source == "" {
+		if dbg, ok := s.GetStack(1); ok {
+			if _, err := s.GetInfo("Sl", dbg, LNil); err == nil && dbg.CurrentLine > 0 {
+				e.Source, e.Line = dbg.Source, dbg.CurrentLine
+			}
+		}
+	}
+	if e.Traceback == "" {
+		e.Traceback = traceback(s, 1)
+	}
+	s.Error(errorValue(s, e), 1)
+}
+
+// AsError convert the error returned by LState.PCall to Error, other errors are returned as is
+func AsError(err error) error {
+	var ae *ApiError
+	if !errors.As(err, &ae) {
+		return err
+	}
+	if ud, ok := ae.Object.(*LUserData); ok {
+		if e, ok := ud.Value.(*Error); ok {
+			if e.Traceback == "" {
+				e.Traceback = ae.StackTrace
+			}
+			return e
+		}
+	}
+	e := &Error{Traceback: ae.StackTrace, Cause: ae.Cause}
+	e.Message = e.locate(ae.Object.String(), ae.StackTrace)
+	return e
+}
+
+// traceback the stack traceback of LState from level
+func traceback(s *LState, level int) string {
+	b := new(strings.Builder)
+	b.WriteString("stack traceback:")
+	for dbg, ok := s.GetStack(level); ok; dbg, ok = s.GetStack(level) {
+		name := "?"
+		if _, err := s.GetInfo("n", dbg, LNil); err == nil && dbg.Name != "" {
+			switch {
+			case dbg.Name == "main chunk":
+				name = dbg.Name
+			case dbg.Name[0] == '<' || dbg.Name[0] == '(':
+				name = "function " + dbg.Name
+			default:
+				name = "function '" + dbg.Name + "'"
+			}
+		}
+		fmt.Fprintf(b, "\n\t%s in %s", s.Where(level), name)
+		level++
+	}
+	return b.String()
+}
+
+// errorValue the error userdata of Error
+func errorValue(s *LState, e *Error) *LUserData {
+	ud := s.NewUserData()
+	ud.Value = e
+	ud.Metatable = errorMetatable(s)
+	return ud
+}
+
+// checkError check the receiver of error methods
+func checkError(s *LState) *Error {
+	if e, ok := s.CheckUserData(1).Value.(*Error); ok {
+		return e
+	}
+	s.ArgError(1, "error expected")
+	return nil
+}
+
+// errorMetatable the metatable of error userdata, create if not exists
+func errorMetatable(s *LState) *LTable {
+	if mt, ok := s.G.Registry.RawGetString(errorKey).(*LTable); ok {
+		return mt
+	}
+	mt := s.NewTable()
+	methods := s.SetFuncs(s.NewTable(), map[string]LGFunction{
+		"message": func(s *LState) int {
+			s.Push(LString(checkError(s).Message))
+			return 1
+		},
+		"code": func(s *LState) int {
+			s.Push(LNumber(checkError(s).Code))
+			return 1
+		},
+		"traceback": func(s *LState) int {
+			s.Push(LString(checkError(s).Traceback))
+			return 1
+		},
+		"cause": func(s *LState) int {
+			if e := checkError(s); e.Cause != nil {
+				s.Push(LString(e.Cause.Error()))
+			} else {
+				s.Push(LNil)
+			}
+			return 1
+		},
+	})
+	mt.RawSetString("__index", methods)
+	mt.RawSetString("__tostring", s.NewFunction(func(s *LState) int {
+		s.Push(LString(checkError(s).text()))
+		return 1
+	}))
+	mt.RawSetString("__concat", s.NewFunction(func(s *LState) int {
+		s.Push(LString(concatText(s, 1) + concatText(s, 2)))
+		return 1
+	}))
+	mt.RawSetString("__metatable", LString("error"))
+	s.G.Registry.RawSetString(errorKey, mt)
+	return mt
+}
+
+// concatText the text of operand n of concatenation, error userdata, string or number
+func concatText(s *LState, n int) string {
+	switch v := s.Get(n).(type) {
+	case LString, LNumber:
+		return v.String()
+	case *LUserData:
+		if e, ok := v.Value.(*Error); ok {
+			return e.text()
+		}
+	}
+	s.RaiseError("attempt to concatenate a %s value", s.Get(n).Type().String())
+	return ""
+}
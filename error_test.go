@@ -0,0 +1,75 @@
+package glu
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"strings"
+	"testing"
+)
+
+var errTestNotFound = errors.New("not found")
+
+func init() {
+	fn.Panic(Register(NewModule("errorTest", `structured errors`, true).
+		AddFunc("find", `(key string) 	 always fails`, RaiseErrorLG(func(s *LState) int {
+			panic(fmt.Errorf("find %s: %w", s.CheckString(1), errTestNotFound))
+		})).
+		AddFunc("coded", `() 	 fails with code`, func(s *LState) int {
+			Throw(s, Errorf(42, "bad request"))
+			return 0
+		}).
+		AddFunc("wrap", `() 	 fails with wrapped error`, func(s *LState) int {
+			Throw(s, fmt.Errorf("wrap: %w", &Error{Message: "inner", Traceback: "stack traceback:\n\t[G]: ?"}))
+			return 0
+		})))
+}
+
+func TestError(t *testing.T) {
+	err := ExecuteCode(`
+		local m=require('errorTest')
+		local ok,err=pcall(function() m.find('a') end)
+		assert(not ok and err:message()=='find a: not found', err:message())
+		assert(err:cause()=='find a: not found' and err:code()==0)
+		assert(string.find(err:traceback(),'stack traceback'))
+		assert(string.find(tostring(err),'<string>:3: find a',1,true),tostring(err))
+		ok,err=pcall(m.coded)
+		assert(err:code()==42 and err:cause()==nil and err:message()=='bad request')
+		m.find('b')
+	`, 0, 0, nil, nil)
+	var e *Error
+	if !errors.Is(err, errTestNotFound) || !errors.As(err, &e) {
+		t.Fatal("should keep cause", err)
+	}
+	if e.Source != "<string>" || e.Line != 10 || e.Message != "find b: not found" || !strings.Contains(e.Traceback, "stack traceback") {
+		t.Fatalf("bad error %#v", e)
+	}
+	err = ExecuteCode(`
+		local ok,err=pcall(require('errorTest').coded)
+		error(err)
+	`, 0, 0, nil, nil)
+	if !errors.As(err, &e) || e.Code != 42 || e.Message != "bad request" {
+		t.Fatal("should raise the error again", err)
+	}
+	err = ExecuteCode(`
+		local x=1
+		error('boom')
+	`, 0, 0, nil, nil)
+	if !errors.As(err, &e) || e.Message != "boom" || e.Line != 3 || e.Cause != nil || !strings.HasPrefix(err.Error(), "<string>:3: boom\nstack traceback:") {
+		t.Fatalf("should locate lua error %#v", err)
+	}
+	err = ExecuteCode(`error('dial tcp 127.0.0.1:5432: refused', 0)`, 0, 0, nil, nil)
+	if !errors.As(err, &e) || e.Source != "" || e.Line != 0 || e.Message != "dial tcp 127.0.0.1:5432: refused" {
+		t.Fatalf("should not locate message without position %#v", err)
+	}
+	if err = ExecuteCode(`
+		local ok,err=pcall(require('errorTest').coded)
+		assert('x: '..err=='x: bad request')
+		assert((err..1):sub(-12)=='bad request1')
+		ok,err=pcall(require('errorTest').wrap)
+		assert(err:message()=='wrap: inner',err:message())
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package glu
+
+import (
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"reflect"
+)
+
+// ErrorConvention how a trailing error result of Go function is returned to Lua
+type ErrorConvention int
+
+const (
+	// ErrorRaise raise the error as Lua error
+	ErrorRaise ErrorConvention = iota
+	// ErrorReturn return nil for each result and the error message, or the results and nil when no error
+	ErrorReturn
+)
+
+// Fn adapt a typed Go function to LGFunction, which can be used in Module.AddFunc and Type.AddMethod.
+//
+// Arguments are checked by Check helpers (CheckString, CheckInt ...),
+// the tables are converted to slice or map, userdata are extracted by the value type.
+// Results are packed by Pack, a trailing error result is processed by the optional ErrorConvention (default ErrorRaise).
+//
+// When used as method, the first parameter is the receiver.
+func Fn[F any](f F, convention ...ErrorConvention) LGFunction {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		panic(fmt.Errorf("require a function but got %T", f))
+	}
+	conv := ErrorRaise
+	if len(convention) > 0 {
+		conv = convention[0]
+	}
+	return func(s *LState) int {
+		return invoke(s, fv, 1, conv)
+	}
+}
+
+// Signature generate help signature of a Go function, such as `(string,int)string`.
+func Signature[F any](f F) string {
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func {
+		panic(fmt.Errorf("require a function but got %T", f))
+	}
+	return signature(t, 0)
+}
@@ -0,0 +1,52 @@
+package glu
+
+import (
+	"errors"
+	"github.com/ZenLiuCN/fn"
+	"strings"
+	"testing"
+)
+
+func init() {
+	greet := func(name string, n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("negative times")
+		}
+		return strings.Repeat("hi "+name+";", n), nil
+	}
+	fn.Panic(Register(NewModule("fnTest", `typed function adapters`, true).
+		AddFunc("greet", Signature(greet), Fn(greet)).
+		AddFunc("tryGreet", Signature(greet), Fn(greet, ErrorReturn)).
+		AddFunc("join", Signature(strings.Join), Fn(strings.Join)).
+		AddFunc("keys", `(table)int`, Fn(func(m map[string]int) int { return len(m) }))))
+}
+
+func TestSignature(t *testing.T) {
+	if s := Signature(strings.Join); s != "({string},string)string" {
+		t.Fatal(s)
+	}
+	if s := Signature(func(v ...int) (bool, error) { return true, nil }); s != "(int ...)bool" {
+		t.Fatal(s)
+	}
+}
+
+func TestFn(t *testing.T) {
+	err := ExecuteCode(`
+		local m=require('fnTest')
+		print(m.help('?'))
+		assert(m.greet('a',2)=='hi a;hi a;')
+		local ok,err=pcall(m.greet,'a',-1)
+		assert(not ok and string.find(err,'negative times'))
+		ok,err=pcall(m.greet,'a','b')
+		assert(not ok)
+		local r,e=m.tryGreet('a',1)
+		assert(r=='hi a;' and e==nil)
+		r,e=m.tryGreet('a',-1)
+		assert(r==nil and e=='negative times')
+		assert(m.join({'a','b'},',')=='a,b')
+		assert(m.keys({a=1,b=2})==2)
+	`, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
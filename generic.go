@@ -145,7 +145,7 @@ func Raise(s *lua.LState, act func() int) (ret int) {
 		if r := recover(); r != nil {
 			switch er := r.(type) {
 			case error:
-				s.RaiseError(er.Error())
+				s.RaiseError("%s", er.Error())
 			case string:
 				s.RaiseError(`failure: %s`, er)
 			default:
@@ -164,7 +164,7 @@ func RaiseLG(act lua.LGFunction) lua.LGFunction {
 			if r := recover(); r != nil {
 				switch er := r.(type) {
 				case error:
-					s.RaiseError(er.Error())
+					s.RaiseError("%s", er.Error())
 				case string:
 					s.RaiseError(`%s`, er)
 				default:
@@ -176,3 +176,28 @@ func RaiseLG(act lua.LGFunction) lua.LGFunction {
 		return act(s)
 	}
 }
+
+// RaiseError recover panic and raise error to Lua like Raise, but the error values are raised as error userdata by Throw
+func RaiseError(s *lua.LState, act func() int) (ret int) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch er := r.(type) {
+			case *lua.ApiError:
+				panic(er)
+			case error:
+				Throw(s, er)
+			default:
+				s.RaiseError(`failure: %s`, er)
+			}
+			ret = 0
+		}
+	}()
+	return act()
+}
+
+// RaiseErrorLG recover panic and raise error with lua.LGFunction like RaiseLG, but the error values are raised as error userdata by Throw
+func RaiseErrorLG(act lua.LGFunction) lua.LGFunction {
+	return func(s *lua.LState) int {
+		return RaiseError(s, func() int { return act(s) })
+	}
+}
@@ -2,7 +2,6 @@ package glu
 
 import (
 	. "github.com/yuin/gopher-lua"
-	"strings"
 )
 
 var (
@@ -27,6 +26,11 @@ func (c glu) CheckChunk(s *LState, n int) Chunk {
 	return nil
 }
 func (c glu) PreLoad(l *LState) {
+	c.preload(l, DefaultRegistry)
+}
+
+// preload the global functions, help() lists modules of the Registry
+func (c glu) preload(l *LState, r *Registry) {
 	l.SetGlobal("chunk", l.NewFunction(func(s *LState) int {
 		chunk, err := CompileChunk(s.CheckString(1), s.CheckString(2))
 		if err != nil {
@@ -42,34 +46,7 @@ func (c glu) PreLoad(l *LState) {
 	}))
 	l.SetGlobal(HelpFunc, l.NewFunction(func(s *LState) int {
 		if s.GetTop() < 1 {
-			if i, ok := c["mod"]; ok {
-				s.Push(LString(i))
-				return 1
-			}
-			sub := new(strings.Builder)
-			sub.WriteString(HelpHelp)
-			sub.WriteString("\nPreload modules:\n")
-			keys := make([]string, 0)
-			l.G.Global.
-				RawGetString("package").(*LTable).
-				RawGetString("preload").(*LTable).
-				ForEach(func(k LValue, _ LValue) {
-					sub.WriteString(k.String() + "\n")
-					keys = append(keys, k.String())
-				})
-			sub.WriteString("\nLoaded modules:\n")
-		out:
-			for name := range moduleNames {
-				for _, n := range keys {
-					if n == name {
-						continue out
-					}
-				}
-				sub.WriteString(name + "\n")
-			}
-			i := sub.String()
-			s.Push(LString(i))
-			c["mod"] = i
+			s.Push(LString(r.helpModules(s)))
 			return 1
 		}
 		t := s.CheckString(1)
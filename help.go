@@ -0,0 +1,341 @@
+package glu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type (
+	// HelpParam describe a parameter or a return value
+	HelpParam struct {
+		Name    string `json:"name,omitempty"`
+		Type    string `json:"type,omitempty"`
+		Default string `json:"default,omitempty"`
+		Help    string `json:"help,omitempty"`
+	}
+	// HelpInfo structured help for a function or method
+	HelpInfo struct {
+		Description string      `json:"description,omitempty"`
+		Params      []HelpParam `json:"params,omitempty"`
+		Returns     []HelpParam `json:"returns,omitempty"`
+		Examples    []string    `json:"examples,omitempty"`
+		Since       string      `json:"since,omitempty"`
+		Deprecated  string      `json:"deprecated,omitempty"`
+	}
+	// MemberDoc the document of a function, method, field or operator
+	MemberDoc struct {
+		Name string    `json:"name"`
+		Help string    `json:"help,omitempty"`
+		Info *HelpInfo `json:"info,omitempty"`
+	}
+	// ModuleDoc the document of a Modular
+	ModuleDoc struct {
+		Name        string      `json:"name"`
+		Help        string      `json:"help,omitempty"`
+		Type        bool        `json:"type,omitempty"` //is a Type
+		Top         bool        `json:"top,omitempty"`
+		Depends     []string    `json:"depends,omitempty"`
+		Extends     string      `json:"extends,omitempty"` //parent Type
+		Constructor *MemberDoc  `json:"constructor,omitempty"`
+		Functions   []MemberDoc `json:"functions,omitempty"`
+		Fields      []MemberDoc `json:"fields,omitempty"`
+		Methods     []MemberDoc `json:"methods,omitempty"`
+		Properties  []MemberDoc `json:"properties,omitempty"`
+		Operators   []MemberDoc `json:"operators,omitempty"`
+		Submodules  []ModuleDoc `json:"submodules,omitempty"`
+	}
+	// HelpFormat the format of ExportHelp
+	HelpFormat int
+	// documented Modular which can export ModuleDoc
+	documented interface {
+		document() ModuleDoc
+	}
+)
+
+const (
+	HelpMarkdown HelpFormat = iota // Markdown API reference
+	HelpJSON                       // JSON array of ModuleDoc
+)
+
+// String the signature style help: `(name type=default,...)returns 	 description`
+func (h *HelpInfo) String() string {
+	if h == nil {
+		return ""
+	}
+	b := new(strings.Builder)
+	b.WriteRune('(')
+	for i, p := range h.Params {
+		if i > 0 {
+			b.WriteRune(',')
+		}
+		b.WriteString(p.Name)
+		if p.Type != "" {
+			if p.Name != "" {
+				b.WriteRune(' ')
+			}
+			b.WriteString(p.Type)
+		}
+		if p.Default != "" {
+			b.WriteRune('=')
+			b.WriteString(p.Default)
+		}
+	}
+	b.WriteRune(')')
+	for i, p := range h.Returns {
+		if i > 0 {
+			b.WriteRune(',')
+		}
+		if p.Type != "" {
+			b.WriteString(p.Type)
+		} else {
+			b.WriteString(p.Name)
+		}
+	}
+	if h.Description != "" {
+		b.WriteString(" \t ")
+		b.WriteString(h.Description)
+	}
+	if h.Deprecated != "" {
+		b.WriteString(" \t deprecated: ")
+		b.WriteString(h.Deprecated)
+	}
+	return b.String()
+}
+
+// operatorNames the metatable field name and the symbol of each Operate
+var operatorNames = map[Operate][2]string{
+	OPERATE_ADD:      {"__add", "+"},
+	OPERATE_SUB:      {"__sub", "-"},
+	OPERATE_MUL:      {"__mul", "*"},
+	OPERATE_DIV:      {"__div", "/"},
+	OPERATE_UNM:      {"__unm", "-"},
+	OPERATE_MOD:      {"__mod", "%"},
+	OPERATE_POW:      {"__pow", "^"},
+	OPERATE_CONCAT:   {"__concat", ".."},
+	OPERATE_EQ:       {"__eq", "=="},
+	OPERATE_LT:       {"__lt", "<"},
+	OPERATE_LE:       {"__le", "<="},
+	OPERATE_LEN:      {"__len", "#"},
+	OPERATE_INDEX:    {"__index", "[]"},
+	OPERATE_NEWINDEX: {"__newindex", "[]="},
+	OPERATE_TOSTRING: {"__tostring", "tostring"},
+	OPERATE_CALL:     {"__call", "()"},
+}
+
+// operatorName the metatable field name and the symbol of Operate
+func operatorName(op Operate) (name string, sym string) {
+	v, ok := operatorNames[op]
+	if !ok {
+		panic(fmt.Errorf("unsupported operators of %d", op))
+	}
+	return v[0], v[1]
+}
+
+func docFunc(fun map[string]funcInfo) (r []MemberDoc) {
+	for s, info := range fun {
+		r = append(r, MemberDoc{s, info.Help, info.Info})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return
+}
+func docField(fun map[string]fieldInfo) (r []MemberDoc) {
+	for s, info := range fun {
+		r = append(r, MemberDoc{Name: s, Help: info.Help})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return
+}
+func docProperty(props map[string]propertyInfo) (r []MemberDoc) {
+	for s, info := range props {
+		if info.Setter == nil {
+			r = append(r, MemberDoc{Name: s, Help: "readonly " + info.Help})
+		} else {
+			r = append(r, MemberDoc{Name: s, Help: info.Help})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return
+}
+func docOperator(fun map[Operate]funcInfo) (r []MemberDoc) {
+	for op, info := range fun {
+		name, _ := operatorName(op)
+		r = append(r, MemberDoc{name, info.Help, info.Info})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return
+}
+func docSubModule(mods []Modular) (r []ModuleDoc) {
+	for _, mod := range mods {
+		r = append(r, Document(mod))
+	}
+	return
+}
+
+// Document export ModuleDoc of a Modular
+func Document(m Modular) ModuleDoc {
+	if d, ok := m.(documented); ok {
+		return d.document()
+	}
+	return ModuleDoc{Name: m.GetName(), Help: m.GetHelp(), Top: m.TopLevel()}
+}
+
+func (m *Mod) document() ModuleDoc {
+	return ModuleDoc{
+		Name:       m.Name,
+		Help:       m.Help,
+		Top:        m.Top,
+		Depends:    m.Depends,
+		Functions:  docFunc(m.functions),
+		Fields:     docField(m.fields),
+		Submodules: docSubModule(m.Submodules),
+	}
+}
+func (m *BaseType[T]) document() ModuleDoc {
+	d := m.Mod.document()
+	d.Type = true
+	d.Extends = m.Parent()
+	if m.constructor != nil {
+		d.Constructor = &MemberDoc{Name: "new", Help: m.HelpCtor}
+	}
+	d.Methods = docFunc(m.methods)
+	d.Properties = docProperty(m.properties)
+	d.Operators = docOperator(m.operators)
+	return d
+}
+
+// ExportHelp write API reference of all Modulars in DefaultRegistry
+func ExportHelp(w io.Writer, format HelpFormat) error {
+	return DefaultRegistry.ExportHelp(w, format)
+}
+
+// documents ModuleDoc of all registered Modulars
+func (r *Registry) documents() []ModuleDoc {
+	mods := r.Modulars()
+	docs := make([]ModuleDoc, 0, len(mods))
+	for _, mod := range mods {
+		docs = append(docs, Document(mod))
+	}
+	return docs
+}
+
+// ExportHelp write API reference of all registered Modulars
+func (r *Registry) ExportHelp(w io.Writer, format HelpFormat) error {
+	docs := r.documents()
+	return WriteHelp(w, format, docs...)
+}
+
+// WriteHelp write ModuleDoc in the format
+func WriteHelp(w io.Writer, format HelpFormat, docs ...ModuleDoc) error {
+	switch format {
+	case HelpJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(docs)
+	case HelpMarkdown:
+		b := new(strings.Builder)
+		for _, d := range docs {
+			markdownModule(b, d, "", 1)
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	default:
+		return fmt.Errorf("unsupported help format %d", format)
+	}
+}
+
+func markdownModule(b *strings.Builder, d ModuleDoc, prefix string, level int) {
+	name := d.Name
+	if prefix != "" {
+		name = prefix + "." + d.Name
+	}
+	h := strings.Repeat("#", level)
+	if d.Type {
+		fmt.Fprintf(b, "%s type `%s`\n\n", h, name)
+	} else {
+		fmt.Fprintf(b, "%s module `%s`\n\n", h, name)
+	}
+	if d.Help != "" {
+		b.WriteString(d.Help)
+		b.WriteString("\n\n")
+	}
+	if len(d.Depends) > 0 {
+		fmt.Fprintf(b, "depends on: `%s`\n\n", strings.Join(d.Depends, "`, `"))
+	}
+	if d.Extends != "" {
+		fmt.Fprintf(b, "extends: `%s`\n\n", d.Extends)
+	}
+	if level < 6 {
+		h += "#"
+	}
+	if d.Constructor != nil {
+		fmt.Fprintf(b, "%s Constructor\n\n", h)
+		markdownMember(b, name+".new", *d.Constructor)
+		b.WriteRune('\n')
+	}
+	markdownMembers(b, h, "Functions", name+".", d.Functions)
+	markdownMembers(b, h, "Fields", name+".", d.Fields)
+	markdownMembers(b, h, "Methods", name+":", d.Methods)
+	markdownMembers(b, h, "Properties", name+".", d.Properties)
+	markdownMembers(b, h, "Operators", name+" ", d.Operators)
+	for _, sub := range d.Submodules {
+		markdownModule(b, sub, name, level+1)
+	}
+}
+func markdownMembers(b *strings.Builder, h, title, prefix string, members []MemberDoc) {
+	if len(members) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s %s\n\n", h, title)
+	for _, m := range members {
+		markdownMember(b, prefix+m.Name, m)
+	}
+	b.WriteRune('\n')
+}
+func markdownMember(b *strings.Builder, name string, m MemberDoc) {
+	if m.Info == nil {
+		if m.Help != "" {
+			fmt.Fprintf(b, "+ `%s` %s\n", name, m.Help)
+		} else {
+			fmt.Fprintf(b, "+ `%s`\n", name)
+		}
+		return
+	}
+	i := m.Info
+	fmt.Fprintf(b, "+ `%s`", name)
+	if i.Description != "" {
+		fmt.Fprintf(b, " %s", i.Description)
+	}
+	b.WriteRune('\n')
+	if i.Deprecated != "" {
+		fmt.Fprintf(b, "    + **deprecated**: %s\n", i.Deprecated)
+	}
+	if i.Since != "" {
+		fmt.Fprintf(b, "    + since: `%s`\n", i.Since)
+	}
+	for _, p := range i.Params {
+		fmt.Fprintf(b, "    + param `%s` `%s`", p.Name, p.Type)
+		if p.Default != "" {
+			fmt.Fprintf(b, " default `%s`", p.Default)
+		}
+		if p.Help != "" {
+			fmt.Fprintf(b, ": %s", p.Help)
+		}
+		b.WriteRune('\n')
+	}
+	for _, p := range i.Returns {
+		fmt.Fprintf(b, "    + return `%s`", p.Type)
+		if p.Name != "" {
+			fmt.Fprintf(b, " %s", p.Name)
+		}
+		if p.Help != "" {
+			fmt.Fprintf(b, ": %s", p.Help)
+		}
+		b.WriteRune('\n')
+	}
+	for _, e := range i.Examples {
+		fmt.Fprintf(b, "    ```lua\n    %s\n    ```\n", strings.ReplaceAll(e, "\n", "\n    "))
+	}
+}
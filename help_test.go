@@ -0,0 +1,81 @@
+package glu
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"strings"
+	"testing"
+)
+
+func init() {
+	fn.Panic(Register(NewModule("helpTest", `structured help`, true).
+		AddFuncInfo("repeat", &HelpInfo{
+			Description: "repeat the string",
+			Params:      []HelpParam{{Name: "s", Type: "string"}, {Name: "n", Type: "int", Default: "1"}},
+			Returns:     []HelpParam{{Type: "string"}},
+			Examples:    []string{"helpTest.repeat('a',2)"},
+			Since:       "v3.1.0",
+		}, func(s *LState) int {
+			s.Push(LString(strings.Repeat(s.CheckString(1), s.OptInt(2, 1))))
+			return 1
+		}).
+		AddModule(NewSimpleType[string]("Text", `text`, false).
+			AddMethod("len", `()int`, func(s *LState) int {
+				s.Push(LNumber(len(s.CheckUserData(1).Value.(string))))
+				return 1
+			}).
+			AddMethodInfo("upper", &HelpInfo{Description: "upper case", Returns: []HelpParam{{Type: "string"}}}, func(s *LState) int {
+				s.Push(LString(strings.ToUpper(s.CheckUserData(1).Value.(string))))
+				return 1
+			}))))
+}
+
+func TestHelpInfo(t *testing.T) {
+	h := &HelpInfo{
+		Description: "some",
+		Params:      []HelpParam{{Name: "a", Type: "string"}, {Name: "b", Type: "bool", Default: "false"}},
+		Returns:     []HelpParam{{Type: "JSON?"}},
+	}
+	if s := h.String(); s != "(a string,b bool=false)JSON? \t some" {
+		t.Fatal(s)
+	}
+	err := ExecuteCode(`
+		local m=require('helpTest')
+		assert(m.help('repeat')=='helpTest.repeat (s string,n int=1)string \t repeat the string')
+		assert(m['repeat']('a',2)=='aa')
+		assert(m.Text.help('upper')=='Text:upper ()string \t upper case', m.Text.help('upper'))
+	`, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportHelp(t *testing.T) {
+	b := new(bytes.Buffer)
+	if err := ExportHelp(b, HelpMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	md := b.String()
+	if !strings.Contains(md, "# module `helpTest`") || !strings.Contains(md, "+ param `n` `int` default `1`") {
+		t.Fatal(md)
+	}
+	b.Reset()
+	if err := ExportHelp(b, HelpJSON); err != nil {
+		t.Fatal(err)
+	}
+	var docs []ModuleDoc
+	if err := json.Unmarshal(b.Bytes(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, d := range docs {
+		if d.Name == "Point" {
+			found = d.Type && d.Constructor != nil && len(d.Methods) > 0
+		}
+	}
+	if !found {
+		t.Fatal(b.String())
+	}
+}
@@ -34,6 +34,20 @@ func TestNewClient(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+func TestClient_GetAsync(t *testing.T) {
+	if err := glu.ExecuteCode(
+		//language=lua
+		`
+	local c=require('http').Client.new(5)
+	local values,err=require('async').awaitAll({c:getAsync('http://127.0.0.1'),c:getAsync('http://127.0.0.1')})
+	assert(err==nil, err)
+	assert(values[1]:body()=='GET' and values[2]:body()=='GET')
+	local res,err=c:getAsync('http://127.0.0.1:1'):await()
+	assert(res==nil and err~=nil)
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
 func TestClient_Form(t *testing.T) {
 	c := NewClient(time.Second)
 	frm := url.Values{}
@@ -5,6 +5,7 @@ import (
 	"github.com/Jeffail/gabs/v2"
 	"github.com/ZenLiuCN/fn"
 	. "github.com/ZenLiuCN/glu/v3"
+	"github.com/ZenLiuCN/glu/v3/async"
 	"github.com/ZenLiuCN/glu/v3/json"
 	. "github.com/yuin/gopher-lua"
 	"io"
@@ -378,6 +379,15 @@ while (true) do	end
 				}
 				return 2
 			}).
+		AddMethodCast("getAsync", `(url string)Future 	perform GET request on goroutine, the Future resolves to Response`,
+			func(s *LState, c *Client) int {
+				return async.FuncOf(RESPONSE, func(s *LState) func() (*http.Response, error) {
+					u := s.CheckString(2)
+					return func() (*http.Response, error) {
+						return c.Get(u)
+					}
+				})(s)
+			}).
 		AddMethodCast("post", `(url,contentType,data string)(Response?,error?) 	perform POST request`,
 			func(s *LState, c *Client) int {
 				res, err := c.Post(s.CheckString(2), s.CheckString(3), s.CheckString(4))
@@ -480,7 +490,7 @@ while (true) do	end
 			})
 	//endregion
 
-	fn.Panic(Register(MODULE.AddModule(CTX).AddModule(SERVER).AddModule(CLIENT).AddModule(RESPONSE)))
+	fn.Panic(Register(MODULE.AddModule(CTX).AddModule(SERVER).AddModule(CLIENT).AddModule(RESPONSE).DependsOn(json.MODULE.GetName(), async.MODULE.GetName())))
 }
 func executeHandler(chunk *LFunction, c *Ctx) {
 	if err := ExecuteFunction(chunk, 1, 0, func(s *Vm) error {
@@ -0,0 +1,140 @@
+package glu
+
+import (
+	"strings"
+)
+
+// superType a BaseType which can be extended, see Extend
+type superType interface {
+	GetName() string
+	ownMethods() map[string]funcInfo
+	ownOperators() map[Operate]funcInfo
+	ownProperties() map[string]propertyInfo
+	super() superType
+}
+
+// Extend make child inherit methods, properties and operators of parent, the methods and operators of child override inherited ones.
+//
+// @upcast optional converter from child value to parent value, which makes parent Type.Check and Type.CheckSelf accept
+// instances of child and its descendants, for example a child struct embeds parent: `func(c *Child) *Parent { return c.Parent }`.
+// When it is nil, the parent caster must accept child values itself (for example caster of an interface type).
+func Extend[T any, P any](child *BaseType[T], parent *BaseType[P], upcast func(T) P) *BaseType[T] {
+	if child.parent != nil {
+		panic(ErrAlreadyExists)
+	}
+	for p := superType(parent); p != nil; p = p.super() {
+		if p.GetName() == child.GetName() {
+			panic(ErrDependencyCycle)
+		}
+	}
+	child.parent = parent
+	if upcast != nil {
+		// the caster of parent is kept, Check of parent falls back to the descendants
+		parent.subtypes = append(parent.subtypes, func(a any) (v P, ok bool) {
+			if c, ok := child.cast(a); ok {
+				return upcast(c), true
+			}
+			return
+		})
+	}
+	return child
+}
+
+func (m *BaseType[T]) ownMethods() map[string]funcInfo {
+	return m.methods
+}
+func (m *BaseType[T]) ownOperators() map[Operate]funcInfo {
+	return m.operators
+}
+func (m *BaseType[T]) super() superType {
+	if m.parent == nil {
+		return nil
+	}
+	return m.parent
+}
+
+// Parent the name of parent Type, empty if not extends any
+func (m *BaseType[T]) Parent() string {
+	if m.parent == nil {
+		return ""
+	}
+	return m.parent.GetName()
+}
+
+// allMethods own and inherited methods
+func (m *BaseType[T]) allMethods() map[string]funcInfo {
+	if m.parent == nil {
+		return m.methods
+	}
+	r := make(map[string]funcInfo)
+	for p := superType(m); p != nil; p = p.super() {
+		for s, info := range p.ownMethods() {
+			if _, ok := r[s]; !ok {
+				r[s] = info
+			}
+		}
+	}
+	return r
+}
+
+// allOperators own and inherited operators
+func (m *BaseType[T]) allOperators() map[Operate]funcInfo {
+	if m.parent == nil {
+		return m.operators
+	}
+	r := make(map[Operate]funcInfo)
+	for p := superType(m); p != nil; p = p.super() {
+		for op, info := range p.ownOperators() {
+			if _, ok := r[op]; !ok {
+				r[op] = info
+			}
+		}
+	}
+	return r
+}
+
+// helpInheritedReg register help of inherited methods and operators under the parent's name
+func helpInheritedReg[T any](m *BaseType[T], helps map[string]string, mh *strings.Builder) {
+	if m.parent == nil {
+		return
+	}
+	mh.WriteString("extends " + m.parent.GetName() + "\n")
+	seen := make(map[string]struct{}, len(m.methods))
+	for s := range m.methods {
+		seen[s] = holder
+	}
+	props := make(map[string]struct{}, len(m.properties))
+	for s := range m.properties {
+		props[s] = holder
+	}
+	ops := make(map[Operate]struct{}, len(m.operators))
+	for op := range m.operators {
+		ops[op] = holder
+	}
+	for p := m.parent; p != nil; p = p.super() {
+		methods := make(map[string]funcInfo)
+		for s, info := range p.ownMethods() {
+			if _, ok := seen[s]; !ok {
+				seen[s] = holder
+				methods[s] = info
+			}
+		}
+		operators := make(map[Operate]funcInfo)
+		for op, info := range p.ownOperators() {
+			if _, ok := ops[op]; !ok {
+				ops[op] = holder
+				operators[op] = info
+			}
+		}
+		properties := make(map[string]propertyInfo)
+		for s, info := range p.ownProperties() {
+			if _, ok := props[s]; !ok {
+				props[s] = holder
+				properties[s] = info
+			}
+		}
+		helpMethodReg(methods, helps, mh, p.GetName())
+		helpPropertyReg(properties, helps, mh, p.GetName())
+		helpOperatorReg(operators, false, helps, mh, p.GetName())
+	}
+}
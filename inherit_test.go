@@ -0,0 +1,80 @@
+package glu
+
+import (
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"strings"
+	"testing"
+)
+
+type animal struct {
+	name string
+}
+type dog struct {
+	*animal
+	trick string
+}
+type puppy struct {
+	*dog
+	age int
+}
+
+func init() {
+	a := NewTypeCast(func(a any) (v *animal, ok bool) { v, ok = a.(*animal); return }, "Animal", `base animal`, true, `(string)Animal`,
+		func(s *LState) *animal { return &animal{s.CheckString(1)} })
+	a.AddMethodCast("name", `()string 	 the name`, func(s *LState, v *animal) int {
+		s.Push(LString(v.name))
+		return 1
+	}).AddMethodCast("speak", `()string 	 speak`, func(s *LState, v *animal) int {
+		s.Push(LString("..."))
+		return 1
+	}).OverrideCast(OPERATE_TOSTRING, `()string`, func(s *LState, v *animal) int {
+		s.Push(LString("animal " + v.name))
+		return 1
+	}).AddFunc("nameOf", `(Animal)string 	 name of animal`, func(s *LState) int {
+		s.Push(LString(a.Check(s, 1).name))
+		return 1
+	})
+	d := NewTypeCast(func(a any) (v *dog, ok bool) { v, ok = a.(*dog); return }, "Dog", `dog is animal`, true, `(string,string)Dog`,
+		func(s *LState) *dog { return &dog{&animal{s.CheckString(1)}, s.CheckString(2)} })
+	d.AddMethodCast("speak", `()string 	 bark`, func(s *LState, v *dog) int {
+		s.Push(LString("woof " + v.trick))
+		return 1
+	})
+	p := NewTypeCast(func(a any) (v *puppy, ok bool) { v, ok = a.(*puppy); return }, "Puppy", `puppy is dog`, true, `(string,string,int)Puppy`,
+		func(s *LState) *puppy {
+			return &puppy{&dog{&animal{s.CheckString(1)}, s.CheckString(2)}, s.CheckInt(3)}
+		})
+	p.AddMethodCast("age", `()int 	 age`, func(s *LState, v *puppy) int {
+		s.Push(LNumber(v.age))
+		return 1
+	})
+	Extend(d, a, func(d *dog) *animal { return d.animal })
+	Extend(p, d, func(p *puppy) *dog { return p.dog })
+	fn.Panic(Register(a, d, p))
+}
+
+func TestExtend(t *testing.T) {
+	err := ExecuteCode(`
+		local d=Dog.new('rex','sit')
+		assert(d:name()=='rex')
+		assert(d:speak()=='woof sit')
+		assert(tostring(d)=='animal rex')
+		assert(Animal.nameOf(d)=='rex')
+		assert(Animal.new('cat'):speak()=='...')
+		local p=Puppy.new('bit','roll',1)
+		assert(p:name()=='bit' and p:speak()=='woof roll' and p:age()==1)
+		assert(tostring(p)=='animal bit' and Animal.nameOf(p)=='bit')
+		assert(not pcall(Puppy.age,d))
+		return Dog.help('?')
+	`, 0, 1, nil, func(s *Vm) error {
+		h := s.ToString(-1)
+		if !strings.Contains(h, "extends Animal") || !strings.Contains(h, "Animal:name ()string") || !strings.Contains(h, "Dog:speak") || strings.Contains(h, "Animal:speak") {
+			t.Fatal(h)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,122 @@
+package glu
+
+import (
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"sort"
+)
+
+// Isolation the level of pollution detection and restore of pooled Vm, see PoolConfig.Isolation
+type Isolation int
+
+const (
+	// IsolationShallow compare the top level of globals and loaded modules, restore the changed entries
+	IsolationShallow Isolation = iota
+	// IsolationDeep compare all tables reachable from globals and loaded modules, restore the changed entries
+	IsolationDeep
+	// IsolationStrict compare as IsolationDeep, discard the Vm when anything changed
+	IsolationStrict
+)
+
+// snapshotTables the registry tables in snapshot
+var snapshotTables = []string{"FILE*", "_LOADED", "_LOADERS"}
+
+// tableSnapshot the entries of a table
+type tableSnapshot struct {
+	path    string
+	table   *LTable
+	entries map[LValue]LValue
+}
+
+// snapshot record the entries of globals and registry tables, recursively if not IsolationShallow
+func (s *Vm) snapshot() {
+	s.snap = s.snap[:0]
+	deep := s.isolation != IsolationShallow
+	seen := make(map[*LTable]struct{})
+	//breadth first, so the shortest path of table is recorded
+	var queue []tableSnapshot
+	push := func(path string, t *LTable) {
+		if _, ok := seen[t]; !ok {
+			seen[t] = holder
+			queue = append(queue, tableSnapshot{path: path, table: t})
+		}
+	}
+	push("_G", s.G.Global)
+	for _, name := range snapshotTables {
+		if t, ok := s.G.Registry.RawGetString(name).(*LTable); ok {
+			push(name, t)
+		}
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		t.entries = make(map[LValue]LValue)
+		t.table.ForEach(func(k LValue, v LValue) {
+			t.entries[k] = v
+			if c, ok := v.(*LTable); ok && deep {
+				push(keyPath(t.path, k), c)
+			}
+		})
+		s.snap = append(s.snap, t)
+	}
+}
+
+// diff the paths of changed entries, stop at first if not all
+func (s *Vm) diff(all bool) (keys []string) {
+	for _, t := range s.snap {
+		t.table.ForEach(func(k LValue, v LValue) {
+			if (all || keys == nil) && t.entries[k] != v {
+				keys = append(keys, keyPath(t.path, k))
+			}
+		})
+		for k := range t.entries {
+			if !all && keys != nil {
+				return
+			}
+			if t.table.RawGet(k) == LNil {
+				keys = append(keys, keyPath(t.path, k))
+			}
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+// Pollution the paths of entries changed since Snapshot, such as `_G.foo` or `_G.string.foo`.
+//
+// The nested tables are only inspected when Isolation is not IsolationShallow.
+func (s *Vm) Pollution() []string {
+	return s.diff(true)
+}
+
+// Isolation the Isolation level of this Vm
+func (s *Vm) Isolation() Isolation {
+	return s.isolation
+}
+
+// restoreEntries restore the changed entries in place, so the references to those tables are kept
+func (s *Vm) restoreEntries() {
+	for _, t := range s.snap {
+		var extra []LValue
+		t.table.ForEach(func(k LValue, v LValue) {
+			if _, ok := t.entries[k]; !ok {
+				extra = append(extra, k)
+			}
+		})
+		for _, k := range extra {
+			t.table.RawSet(k, LNil)
+		}
+		for k, v := range t.entries {
+			if t.table.RawGet(k) != v {
+				t.table.RawSet(k, v)
+			}
+		}
+	}
+}
+
+func keyPath(path string, k LValue) string {
+	if k.Type() == LTString {
+		return path + "." + k.String()
+	}
+	return fmt.Sprintf("%s[%s]", path, k)
+}
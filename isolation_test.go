@@ -0,0 +1,52 @@
+package glu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsolation(t *testing.T) {
+	pollute := `
+		string.foo=1
+		print=nil
+		leak=true
+	`
+	for _, c := range []struct {
+		level     Isolation
+		pollution string
+		clean     bool
+		discard   bool
+	}{
+		{IsolationShallow, "_G.leak,_G.print", false, false},
+		{IsolationDeep, "_G.leak,_G.print,_G.string.foo", true, false},
+		{IsolationStrict, "_G.leak,_G.print,_G.string.foo", true, true},
+	} {
+		p := MustNewPool(PoolConfig{Isolation: c.level})
+		v := p.Get()
+		if v.Polluted() || v.Pollution() != nil {
+			t.Fatal("should clean", v.Pollution())
+		}
+		if err := v.DoString(pollute); err != nil {
+			t.Fatal(err)
+		}
+		if r := strings.Join(v.Pollution(), ","); r != c.pollution {
+			t.Fatal(c.level, "pollution", r)
+		}
+		p.Put(v)
+		if s := p.Stats(); s.Polluted != 1 || (s.Discarded == 1) != c.discard {
+			t.Fatalf("%d %+v", c.level, s)
+		}
+		w := p.Get()
+		if (w == v) == c.discard || (c.discard && !v.IsClosed()) {
+			t.Fatal(c.level, "strict should discard")
+		}
+		if err := w.DoString(`assert(print and leak==nil)`); err != nil {
+			t.Fatal(c.level, err)
+		}
+		if err := w.DoString(`assert(string.foo==nil)`); (err == nil) != c.clean {
+			t.Fatal(c.level, "deep should restore nested table", err)
+		}
+		p.Put(w)
+		p.Shutdown()
+	}
+}
@@ -70,7 +70,16 @@ func init() {
 				v := s.CheckString(1)
 				g := fn.Panic1(ParseJSON([]byte(v)))
 				return JSON.New(s, g)
-			})
+			}).
+		AddEnum("Type", `the value type of JSON`, map[string]any{
+			"Nil":     0,
+			"String":  1,
+			"Number":  2,
+			"Boolean": 3,
+			"Array":   4,
+			"Object":  5,
+		})
+	kind := MODULE.Enum("Type").Member
 	JSON = NewTypeCast(func(a any) (v *Container, ok bool) { v, ok = a.(*Container); return }, "JSON", `json.JSON`, false, `(string?)JSON? 	 create JSON instance.`,
 		func(s *LState) *Container {
 			if s.GetTop() == 1 {
@@ -139,20 +148,20 @@ func init() {
 				}
 				return 0
 			}).
-		AddMethodCast("type", `()int  	 fetch JSON type: 0 nil,1 string,2 number,3 boolean,4 array,5 object.`,
+		AddMethodCast("type", `()json.Type  	 fetch JSON type, the value is member of enum json.Type.`,
 			func(s *LState, v *Container) int {
 				if _, ok := v.Data().(string); ok {
-					s.Push(LNumber(1))
+					s.Push(kind("String"))
 				} else if _, ok = v.Data().(float64); ok {
-					s.Push(LNumber(2))
+					s.Push(kind("Number"))
 				} else if _, ok = v.Data().(bool); ok {
-					s.Push(LNumber(3))
+					s.Push(kind("Boolean"))
 				} else if _, ok = v.Data().([]any); ok {
-					s.Push(LNumber(4))
+					s.Push(kind("Array"))
 				} else if _, ok = v.Data().(map[string]any); ok {
-					s.Push(LNumber(5))
+					s.Push(kind("Object"))
 				} else {
-					s.Push(LNumber(0))
+					s.Push(kind("Nil"))
 				}
 				return 1
 			}).
@@ -40,6 +40,8 @@ local js='{"a":{"a1":1,"a2":true,"a3":"123"},"b":[1,2,3],"c":"c","d":1,"e":1.2,"
 local j=json.JSON.new(js)
 assert(j:json()==js)
 assert(j:get('a'):type()==5)
+assert(j:get('a'):type()==json.Type.Object and json.Type[j:get('b'):type()]=='Array')
+assert(not pcall(function() json.Type.Object=1 end))
 assert(j:isObject('a'))
 assert(j:isArray('a')==false)
 assert(j:isArray('x')==false)
@@ -0,0 +1,97 @@
+package glu
+
+import (
+	"errors"
+	. "github.com/yuin/gopher-lua"
+	"time"
+)
+
+// ErrLimitExceeded execution exceeded a resource limit of VmPool
+var ErrLimitExceeded = errors.New("resource limit exceeded")
+
+// the kinds of limit in LimitError
+const (
+	LimitCallStack = "call stack"
+	LimitRegistry  = "registry"
+	LimitTimeout   = "timeout"
+)
+
+// LimitError the error of execution exceeded a limit of PoolOptions, errors.Is matches ErrLimitExceeded
+type LimitError struct {
+	Limit string //the kind of limit: LimitCallStack, LimitRegistry or LimitTimeout
+	Cause error  //the error raised in LState
+}
+
+func (e *LimitError) Error() string {
+	return ErrLimitExceeded.Error() + " of " + e.Limit + ": " + e.Cause.Error()
+}
+func (e *LimitError) Unwrap() error {
+	return e.Cause
+}
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// PoolOptions the LState options and execution limits of a VmPool, used instead of the global Option
+type PoolOptions struct {
+	// Options of LState: CallStackSize limits the call depth, RegistrySize and RegistryMaxSize limit the data stack
+	Options
+	// Timeout the wall-clock budget of each Execute call on the pool, 0 for no limit
+	Timeout time.Duration
+	// MaxSize the max Vms in use, 0 for unbounded
+	MaxSize int
+	// MaxIdle the max Vms kept in pool, 0 for unbounded
+	MaxIdle int
+	// IdleTimeout close the Vms idle longer than it, 0 for never
+	IdleTimeout time.Duration
+	// WaitTimeout the max wait of Get when MaxSize reached, 0 for wait forever
+	WaitTimeout time.Duration
+}
+
+// CreatePoolWithOptions create pool with options and limits, see NewPool
+func CreatePoolWithOptions(o PoolOptions) *VmPool {
+	return MustNewPool(PoolConfig{PoolOptions: o, Manual: !Auto, InitialSize: InitialSize})
+}
+
+// Options the PoolOptions of this pool
+func (pl *VmPool) Options() PoolOptions {
+	return pl.config.PoolOptions
+}
+
+// guarded call the function below the arguments on stack through a Go function,
+// which classifies the limit exceeded before the frames and data stack unwound by PCall.
+func guarded(l *LState, limit *string) *LFunction {
+	return l.NewFunction(func(s *LState) int {
+		defer func() {
+			if r := recover(); r != nil {
+				*limit = exceeded(s)
+				panic(r)
+			}
+		}()
+		s.Call(s.GetTop()-1, MultRet)
+		return s.GetTop()
+	})
+}
+
+// exceeded the kind of limit reached by the failed LState, empty if none.
+// The resources are probed, so an error raised with the same message by a function is not a limit.
+func exceeded(l *LState) string {
+	switch {
+	case full(l, func() { l.Push(LNil); l.Pop(1) }):
+		return LimitRegistry
+	case full(l, func() { l.Push(l.NewFunction(func(*LState) int { return 0 })); l.Call(0, 0) }):
+		return LimitCallStack
+	}
+	return ""
+}
+
+// full check if alloc fails
+func full(l *LState, alloc func()) (failed bool) {
+	defer func() {
+		if recover() != nil {
+			failed = true
+		}
+	}()
+	alloc()
+	return
+}
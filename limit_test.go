@@ -0,0 +1,57 @@
+package glu
+
+import (
+	"context"
+	"errors"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(NewModule("fake", ``, true).
+		AddFunc("overflow", `(string)`, func(s *LState) int {
+			s.RaiseError(s.CheckString(1))
+			return 0
+		})); err != nil {
+		t.Fatal(err)
+	}
+	p := MustNewPool(PoolConfig{Registry: r, PoolOptions: PoolOptions{
+		Options: Options{CallStackSize: 64, RegistrySize: 1024, RegistryMaxSize: 4096},
+		Timeout: 50 * time.Millisecond,
+	}})
+	defer p.Shutdown()
+	ctx := context.Background()
+	for code, limit := range map[string]string{
+		`local function f(n) return f(n+1)+1 end f(1)`:            LimitCallStack,
+		`local t={} for i=1,10000 do t[i]=i end return unpack(t)`: LimitRegistry,
+		`while true do end`: LimitTimeout,
+	} {
+		err := p.ExecuteCode(ctx, code, 0, 0, nil, nil)
+		var le *LimitError
+		if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &le) || le.Limit != limit {
+			t.Fatal(code, "should exceed", limit, err)
+		}
+		if len(p.saved) != 0 {
+			t.Fatal("offending vm should not be returned to pool")
+		}
+	}
+	for _, code := range []string{`error('stack overflow')`, `error('registry overflow',0)`, `local function f() error('stack overflow') end f()`,
+		`require('fake').overflow('stack overflow')`, `require('fake').overflow('registry overflow')`} {
+		if err := p.ExecuteCode(ctx, code, 0, 0, nil, nil); err == nil || errors.Is(err, ErrLimitExceeded) {
+			t.Fatal(code, "user error should not be a limit", err)
+		}
+	}
+	if err := p.ExecuteCode(ctx, `while true do end`, 0, 0, nil, nil); !errors.Is(err, ErrTimeout) {
+		t.Fatal("timeout limit should also be a timeout", err)
+	}
+	if err := p.ExecuteCode(ctx, `local function f(n) if n==0 then return 0 end return f(n-1)+1 end return f(10)`, 0, 1, nil, func(s *Vm) error {
+		if s.Get(-1) != LNumber(10) {
+			return errors.New("bad result")
+		}
+		return nil
+	}); err != nil || len(p.saved) != 1 {
+		t.Fatal("healthy vm should be returned", err)
+	}
+}
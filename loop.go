@@ -0,0 +1,145 @@
+package glu
+
+import (
+	"container/heap"
+	"context"
+	. "github.com/yuin/gopher-lua"
+	"time"
+)
+
+// loopKey the registry key of EventLoop
+const loopKey = "_LOOP"
+
+func init() {
+	// drop the pending timers
+	OnReset(func(l *LState) {
+		l.G.Registry.RawSetString(loopKey, LNil)
+	})
+}
+
+// EventLoop the timers of a LState, the callbacks are executed on the owning LState by RunLoop.
+//
+// **Note** EventLoop is not goroutine safe, it should only be used on the owning LState.
+type EventLoop struct {
+	seq    int
+	timers map[int]*loopTimer
+	queue  timerQueue
+}
+
+type loopTimer struct {
+	id       int
+	at       time.Time
+	interval time.Duration //repeat interval, 0 for once
+	fn       *LFunction
+	args     []LValue
+	index    int
+}
+
+// LoopOf the EventLoop of LState, create if not exists
+func LoopOf(l *LState) *EventLoop {
+	if ud, ok := l.G.Registry.RawGetString(loopKey).(*LUserData); ok {
+		if e, ok := ud.Value.(*EventLoop); ok {
+			return e
+		}
+	}
+	e := &EventLoop{timers: make(map[int]*loopTimer)}
+	ud := l.NewUserData()
+	ud.Value = e
+	l.G.Registry.RawSetString(loopKey, ud)
+	return e
+}
+
+// Schedule call fn with args after delay, repeat with interval if interval greater than 0. Returns the timer id.
+func (e *EventLoop) Schedule(fn *LFunction, delay time.Duration, interval time.Duration, args ...LValue) int {
+	e.seq++
+	t := &loopTimer{id: e.seq, at: time.Now().Add(delay), interval: interval, fn: fn, args: args}
+	e.timers[t.id] = t
+	heap.Push(&e.queue, t)
+	return t.id
+}
+
+// Clear cancel the timer, returns false if not exists
+func (e *EventLoop) Clear(id int) bool {
+	t, ok := e.timers[id]
+	if !ok {
+		return false
+	}
+	delete(e.timers, id)
+	heap.Remove(&e.queue, t.index)
+	return true
+}
+
+// Pending the count of scheduled timers
+func (e *EventLoop) Pending() int {
+	return len(e.timers)
+}
+
+// RunLoop run the timer callbacks of Vm until no timers remain, or the context of Vm is cancelled.
+//
+// Returns the error of context or the first error raised by callbacks.
+func RunLoop(vm *Vm) error {
+	ctx := vm.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return LoopOf(vm.LState).Run(ctx, vm.LState)
+}
+
+// Run the timer callbacks on the owning LState until no timers remain or ctx cancelled
+func (e *EventLoop) Run(ctx context.Context, l *LState) error {
+	for len(e.queue) > 0 {
+		t := e.queue[0]
+		if d := time.Until(t.at); d > 0 {
+			w := time.NewTimer(d)
+			select {
+			case <-w.C:
+			case <-ctx.Done():
+				w.Stop()
+				return ctx.Err()
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		if t.interval > 0 {
+			t.at = time.Now().Add(t.interval)
+			heap.Fix(&e.queue, t.index)
+		} else {
+			e.Clear(t.id)
+		}
+		l.Push(t.fn)
+		for _, arg := range t.args {
+			l.Push(arg)
+		}
+		if err := l.PCall(len(t.args), 0, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timerQueue the heap of timers ordered by time and id
+type timerQueue []*loopTimer
+
+func (q timerQueue) Len() int { return len(q) }
+func (q timerQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].id < q[j].id
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q timerQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *timerQueue) Push(x any) {
+	t := x.(*loopTimer)
+	t.index = len(*q)
+	*q = append(*q, t)
+}
+func (q *timerQueue) Pop() any {
+	old := *q
+	t := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return t
+}
@@ -24,6 +24,12 @@ type Prepare interface {
 	prepare()
 }
 
+// Dependent Modular which depends on other top level Modulars
+type Dependent interface {
+	// Dependencies names of the Modulars depends on
+	Dependencies() []string
+}
+
 var (
 	//HelpKey the module HelpCache key
 	HelpKey = "?"
@@ -92,63 +98,10 @@ func helpSubModReg(fun []Modular, helps map[string]string, mh *strings.Builder,
 func helpOperatorReg(operators map[Operate]funcInfo, hasMethods bool, helps map[string]string, mh *strings.Builder, mod string) {
 	if len(operators) > 0 {
 		for op, info := range operators {
-			var sym string
-			var name string
-			switch op {
-			case OPERATE_ADD:
-				name = "__add"
-				sym = "+"
-			case OPERATE_SUB:
-				name = "__sub"
-				sym = "-"
-			case OPERATE_MUL:
-				name = "__mul"
-				sym = "*"
-			case OPERATE_DIV:
-				name = "__div"
-				sym = "/"
-			case OPERATE_UNM:
-				name = "__unm"
-				sym = "-"
-			case OPERATE_MOD:
-				name = "__mod"
-				sym = "%"
-			case OPERATE_POW:
-				name = "__pow"
-				sym = "^"
-			case OPERATE_CONCAT:
-				name = "__concat"
-				sym = ".."
-			case OPERATE_EQ:
-				name = "__eq"
-				sym = "=="
-			case OPERATE_LT:
-				name = "__lt"
-				sym = "<"
-			case OPERATE_LE:
-				name = "__le"
-				sym = "<="
-			case OPERATE_LEN:
-				name = "__len"
-				sym = "#"
-			case OPERATE_NEWINDEX:
-				name = "__newindex"
-				sym = "[]="
-			case OPERATE_TOSTRING:
-				name = "__tostring"
-				sym = "tostring"
-			case OPERATE_CALL:
-				name = "__call"
-				sym = "()"
-			case OPERATE_INDEX:
-				if hasMethods {
-					panic(ErrIndexOverrideWithMethods)
-				}
-				name = "__index"
-				sym = "[]"
-			default:
-				panic(fmt.Errorf("unsupported operators of %d", op))
+			if op == OPERATE_INDEX && hasMethods {
+				panic(ErrIndexOverrideWithMethods)
 			}
+			name, sym := operatorName(op)
 			if info.Help != "" {
 				helps[name] = fmt.Sprintf("metatable %s:%s %s\n", mod, sym, info.Help)
 				mh.WriteString(fmt.Sprintf("metatable %s:%s %s\n", mod, sym, info.Help))
@@ -187,4 +140,11 @@ var (
 	ErrAlreadyExists            = errors.New("element already exists")
 	ErrIndexOverrideWithMethods = errors.New("element both have methods and index overrides")
 	ErrIsTop                    = errors.New("element is top module")
+	ErrMissingDependency        = errors.New("missing dependency")
+	ErrDependencyCycle          = errors.New("dependency cycle")
+	ErrNotExists                = errors.New("element not exists")
+	ErrHasDependents            = errors.New("element required by others")
+	ErrPoolExhausted            = errors.New("pool exhausted")
+	ErrNotInUse                 = errors.New("vm not in use of pool")
+	ErrVmClosed                 = errors.New("vm closed")
 )
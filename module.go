@@ -2,8 +2,9 @@
 // glu.Modular and gua.BaseType will inject mod.Help(name string?) method to output HelpCache information.
 // glu.Get: Pool function to get a lua.LState.
 // glu.Put: Pool function to return a lua.LState.
-// glu.modulars: shared module modulars.
-// glu.Auto: config for autoload modules in modulars into lua.LState.
+// glu.DefaultRegistry: shared module modulars, glu.Registry for instance scoped modulars.
+// glu.NewPool: create independently configured pool with glu.PoolConfig.
+// glu.Auto: config for autoload modules in registry into lua.LState.
 package glu
 
 import (
@@ -23,14 +24,22 @@ type (
 		//
 		// @fn the LGFunction
 		AddFunc(name string, help string, fn LGFunction) Module
+		// AddFuncInfo add function to this Module with structured help, the help string is generated by HelpInfo.String
+		AddFuncInfo(name string, info *HelpInfo, fn LGFunction) Module
 		// AddField add value field to this Module (static value)
 		AddField(name string, help string, value LValue) Module
 		// AddFieldSupplier add value field to this Module (static value from a Supplier)
 		AddFieldSupplier(name string, help string, su func(s *LState) LValue) Module
+		// AddEnum add read-only Enum to this Module, see NewEnum
+		AddEnum(name string, help string, members map[string]any) Module
+		// Enum fetch Enum added by AddEnum, nil if not exists
+		Enum(name string) *Enum
 		// AddModule add submodule to this Module
 		//
 		// @mod the Mod , requires Mod.TopLevel is false.
 		AddModule(mod Modular) Module
+		// DependsOn declare top level Modulars this Module depends on, which must be registered before or together.
+		DependsOn(names ...string) Module
 	}
 	fieldInfo struct {
 		Help     string
@@ -40,6 +49,7 @@ type (
 	funcInfo struct {
 		Help string
 		Func LGFunction
+		Info *HelpInfo
 	}
 	//Mod define a Mod only contains Functions and value fields,maybe with Submodules
 	Mod struct {
@@ -49,6 +59,8 @@ type (
 		functions  map[string]funcInfo  //registered functions
 		fields     map[string]fieldInfo //registered fields
 		Submodules []Modular            //registered sub modules
+		Depends    []string             //names of Modulars this depends on
+		enums      map[string]*Enum     //registered enums, also in fields
 		prepared   bool                 //compute helper and other things, should just do once
 		HelpCache  map[string]string    //exported helps for better use
 	}
@@ -68,6 +80,9 @@ func (m *Mod) GetName() string {
 func (m *Mod) GetHelp() string {
 	return m.Help
 }
+func (m *Mod) Dependencies() []string {
+	return m.Depends
+}
 func (m *Mod) prepare() {
 	if m.prepared {
 		return
@@ -104,7 +119,7 @@ func (m *Mod) PreLoad(l *LState) {
 		return
 	}
 	m.prepare()
-	l.PreloadModule(m.Name, func(l *LState) int {
+	preloadTable(l).RawSetString(m.Name, l.NewFunction(func(l *LState) int {
 		mod := l.NewTable()
 		fn := make(map[string]LGFunction)
 		if len(m.functions) > 0 {
@@ -137,7 +152,7 @@ func (m *Mod) PreLoad(l *LState) {
 		}
 		l.Push(mod)
 		return 1
-	})
+	}))
 }
 func (m *Mod) PreloadSubModule(l *LState, t *LTable) {
 	if m.Top {
@@ -185,14 +200,23 @@ func (m *Mod) PreloadSubModule(l *LState, t *LTable) {
 //
 // @fn the LGFunction
 func (m *Mod) AddFunc(name string, help string, fn LGFunction) Module {
+	m.addFunc(name, funcInfo{help, fn, nil})
+	return m
+
+}
+
+// AddFuncInfo add function to this Modular with structured help, the help string is generated by HelpInfo.String
+func (m *Mod) AddFuncInfo(name string, info *HelpInfo, fn LGFunction) Module {
+	m.addFunc(name, funcInfo{info.String(), fn, info})
+	return m
+}
+func (m *Mod) addFunc(name string, info funcInfo) {
 	if m.functions == nil {
 		m.functions = make(map[string]funcInfo)
 	} else if _, ok := m.functions[name]; ok {
 		panic(ErrAlreadyExists)
 	}
-	m.functions[name] = funcInfo{help, fn}
-	return m
-
+	m.functions[name] = info
 }
 
 // AddField add value field to this Modular
@@ -221,6 +245,24 @@ func (m *Mod) AddFieldSupplier(name string, help string, su func(s *LState) LVal
 	return m
 }
 
+// AddEnum add read-only Enum as a field to this Modular
+//
+// @members the member names and values, see NewEnum
+func (m *Mod) AddEnum(name string, help string, members map[string]any) Module {
+	e := NewEnum(name, help, members)
+	m.AddFieldSupplier(name, e.String(), e.Table)
+	if m.enums == nil {
+		m.enums = make(map[string]*Enum)
+	}
+	m.enums[name] = e
+	return m
+}
+
+// Enum fetch Enum added by AddEnum
+func (m *Mod) Enum(name string) *Enum {
+	return m.enums[name]
+}
+
 // AddModule add sub-module to this Modular
 //
 // @mod the Mod **Note** must with TopLevel false.
@@ -232,3 +274,9 @@ func (m *Mod) AddModule(mod Modular) Module {
 	return m
 
 }
+
+// DependsOn declare top level Modulars this Modular depends on.
+func (m *Mod) DependsOn(names ...string) Module {
+	m.Depends = append(m.Depends, names...)
+	return m
+}
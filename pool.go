@@ -1,18 +1,21 @@
 package glu
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	. "github.com/yuin/gopher-lua"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
 var (
-	//Option LState configuration
-	Option      = Options{}
+	//Option LState configuration of CreatePool
+	Option = Options{}
+	//InitialSize initial capacity of CreatePool
 	InitialSize = 4
 	pool        *VmPool
 )
@@ -22,24 +25,33 @@ func MakePool() {
 	pool = CreatePool()
 }
 
-// Get LState from statePool
+// SetPool replace the default pool used by Get, Put and Execute functions
+func SetPool(p *VmPool) {
+	pool = p
+}
+
+// Get LState from statePool, see VmPool.Get
 func Get() *Vm {
-	if pool == nil {
-		MakePool()
+	return defaultPool().Get()
+}
+
+// Put LState back to statePool, the Vm taken from another VmPool is returned to its owner, see VmPool.Put
+func Put(s *Vm) error {
+	if s.pool != nil {
+		return s.pool.Put(s)
 	}
-	return pool.Get()
+	return defaultPool().Put(s)
 }
 
-// Put LState back to statePool
-func Put(s *Vm) {
+func defaultPool() *VmPool {
 	if pool == nil {
 		MakePool()
 	}
-	pool.Put(s)
+	return pool
 }
 
 var (
-	//Auto if true, will autoload modules in modulars
+	//Auto if true, pools created by CreatePool* will autoload modules in modulars
 	Auto = true
 )
 
@@ -47,8 +59,13 @@ var (
 type (
 	Vm struct {
 		*LState
-		env *LTable
-		reg *LTable
+		snap      []tableSnapshot
+		isolation Isolation
+		registry  *Registry //the registry preloaded, nil if not
+		version   uint64    //the registry version preloaded
+		idle      time.Time //the time returned to pool
+		pool      *VmPool   //the owner pool
+		inUse     bool      //taken from the owner pool and not returned
 	}
 )
 
@@ -66,26 +83,29 @@ func setField(source interface{}, fieldName string, fieldVal interface{}) (err e
 	return nil
 }
 
-// Polluted check if the Env is polluted
+// Polluted check if the Env is polluted, see Pollution for details
 func (s *Vm) Polluted() (r bool) {
-	return !s.regEqual() || !s.TabEqualTo(s.G.Global, s.env)
+	return s.diff(false) != nil
+}
+
+// Close the LState, the version of Registry held by the Vm is released
+func (s *Vm) Close() {
+	if s.registry != nil {
+		s.registry.release(s.version)
+		s.registry = nil
+	}
+	if !s.IsClosed() {
+		s.LState.Close()
+	}
 }
 
 // Snapshot take snapshot for Env
 func (s *Vm) Snapshot() *Vm {
-	s.reg = s.regCopy(s.G.Registry)
-	s.env = s.TabCopyNew(s.G.Global)
+	s.snapshot()
 	return s
 }
 func (s *Vm) restore() {
-	//inspect(s)
-	if !s.regEqual() {
-		s.G.Registry = s.regCopy(s.reg)
-	}
-	if !s.TabEqualTo(s.G.Global, s.env) {
-		s.G.Global = s.TabCopyNew(s.env)
-	}
-	//inspect(s)
+	s.restoreEntries()
 	s.G.MainThread = nil
 	s.G.CurrentThread = nil
 	s.Parent = nil
@@ -95,15 +115,6 @@ func (s *Vm) restore() {
 
 var errNotEqual = errors.New("")
 
-func (s *Vm) regEqual() bool {
-	r0 := s.G.Registry
-	r1 := s.reg
-	return s.TabChildEqualTo(r0, r1, "FILE*", "_LOADED", "_LOADERS")
-}
-func (s *Vm) regCopy(r *LTable) *LTable {
-	return s.TabCopyChildNew(r, "FILE*", "_LOADED", "_LOADERS")
-}
-
 func (s *Vm) TabEqualTo(t1 *LTable, t2 *LTable) (r bool) {
 	defer func() {
 		if e := recover(); e == nil {
@@ -206,9 +217,23 @@ func prettyi(v LValue, i int, d int, h map[LValue]struct{}) {
 	}
 }
 
-// Reset reset Env
+var resetHooks []func(l *LState)
+
+// OnReset register hook called when a Vm is reset, such as returned to the pool.
+// Used to drop the states kept outside the globals, such as in registry. It should be called in init.
+func OnReset(hook func(l *LState)) {
+	resetHooks = append(resetHooks, hook)
+}
+
+// Reset reset Env, returns nil if failed or polluted with IsolationStrict
 // @fluent
 func (s *Vm) Reset() (r *Vm) {
+	r, _ = s.reset()
+	return
+}
+
+// reset Env, returns nil if failed, polluted is true if Env restored
+func (s *Vm) reset() (r *Vm, polluted bool) {
 	//safeguard
 	defer func() {
 		rc := recover()
@@ -217,11 +242,18 @@ func (s *Vm) Reset() (r *Vm) {
 		}
 	}()
 	s.LState.Pop(s.LState.GetTop())
+	for _, hook := range resetHooks {
+		hook(s.LState)
+	}
 	if s.Polluted() {
+		if s.isolation == IsolationStrict {
+			return nil, true
+		}
 		// reset global https://github.com/ZenLiuCN/glu/issues/1
 		s.restore()
+		polluted = true
 	}
-	return s
+	return s, polluted
 }
 
 type lib struct {
@@ -272,70 +304,274 @@ func (s *Vm) OpenLibsWithout(names ...string) *Vm {
 
 // VmPool threadsafe LState Pool
 type VmPool struct {
-	m     sync.Mutex
-	saved []*Vm //TODO replace with more effective structure
-	ctor  func() *LState
+	m        sync.Mutex
+	saved    []*Vm //idle Vms, the oldest first
+	config   PoolConfig
+	slots    chan struct{} //Vms in use, nil if unbounded
+	done     chan struct{} //stop the idle eviction
+	stats    PoolStats
+	shutdown bool //the returned Vms are closed
+}
+
+// PoolStats the counters of VmPool
+type PoolStats struct {
+	Created   uint64 //Vms created
+	Reused    uint64 //Vms taken from idle
+	Polluted  uint64 //Vms restored due to global pollution
+	Discarded uint64 //Vms closed: evicted, over MaxIdle, failed to reset or aborted
+	InUse     int    //Vms taken and not returned
+	Idle      int    //Vms in pool
 }
 
 // CreatePoolWith create pool with user defined constructor
 //
 //	BaseMod will auto registered
+//
+// Deprecated: use NewPool with PoolConfig.Constructor
 func CreatePoolWith(ctor func() *LState) *VmPool {
-	return &VmPool{saved: make([]*Vm, 0, InitialSize), ctor: ctor}
+	return MustNewPool(PoolConfig{
+		Constructor: func(Options) *LState { return ctor() },
+		Manual:      true,
+		InitialSize: InitialSize,
+	})
 }
+
+// CreatePool create pool with package variables Option, Auto and InitialSize, see NewPool
 func CreatePool() *VmPool {
-	return &VmPool{saved: make([]*Vm, 0, InitialSize)}
+	return MustNewPool(PoolConfig{PoolOptions: PoolOptions{Options: Option}, Manual: !Auto, InitialSize: InitialSize})
 }
 
+// CreatePoolWithRegistry create pool which preload modules from the Registry instead of DefaultRegistry
+func CreatePoolWithRegistry(r *Registry) *VmPool {
+	return MustNewPool(PoolConfig{PoolOptions: PoolOptions{Options: Option}, Registry: r, Manual: !Auto, InitialSize: InitialSize})
+}
+
+// Registry the Registry of this pool
+func (pl *VmPool) Registry() *Registry {
+	if pl.config.Registry == nil {
+		return DefaultRegistry
+	}
+	return pl.config.Registry
+}
+
+// Get a Vm from pool, when MaxSize of PoolOptions reached, wait until one returned.
+//
+// **Note** Get panics with ErrPoolExhausted if WaitTimeout elapsed, use Acquire to handle it as error.
 func (pl *VmPool) Get() *Vm {
+	v, err := pl.Acquire(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Acquire a Vm from pool, when MaxSize of PoolOptions reached, wait until one returned,
+// or returns ErrPoolExhausted when ctx is done or WaitTimeout elapsed.
+func (pl *VmPool) Acquire(ctx context.Context) (*Vm, error) {
+	if pl.slots != nil {
+		select {
+		case pl.slots <- holder:
+		default:
+			if t := pl.config.WaitTimeout; t > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, t)
+				defer cancel()
+			}
+			select {
+			case pl.slots <- holder:
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%w: %d in use", ErrPoolExhausted, cap(pl.slots))
+			}
+		}
+	}
+	pl.m.Lock()
+	pl.stats.InUse++
+	var x *Vm
+	if n := len(pl.saved); n > 0 {
+		x = pl.saved[n-1]
+		pl.saved = pl.saved[0 : n-1]
+	}
+	pl.m.Unlock()
+	v, err := pl.prepare(x)
 	pl.m.Lock()
 	defer pl.m.Unlock()
-	n := len(pl.saved)
-	if n == 0 {
-		return pl.new()
+	if err != nil {
+		pl.stats.InUse--
+		if x != nil {
+			pl.stats.Discarded++
+			x.Close()
+		}
+		if pl.slots != nil {
+			select {
+			case <-pl.slots:
+			default:
+			}
+		}
+		return nil, err
+	}
+	if x == nil {
+		pl.stats.Created++
+	} else {
+		pl.stats.Reused++
 	}
-	x := pl.saved[n-1]
-	pl.saved = pl.saved[0 : n-1]
-	return x
+	v.inUse = true
+	return v, nil
+}
+
+// prepare create a Vm if x is nil, otherwise sync the idle x with Registry. The panic is returned as error.
+func (pl *VmPool) prepare(x *Vm) (v *Vm, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	if x == nil {
+		return pl.new(), nil
+	}
+	x.sync(pl.Registry())
+	return x, nil
 }
 
 func (pl *VmPool) new() *Vm {
-	if pl.ctor != nil {
-		l := pl.ctor()
-		BaseMod.PreLoad(l)
-		return (&Vm{LState: l}).Snapshot()
+	c := &pl.config
+	var L *LState
+	if c.Constructor != nil {
+		L = c.Constructor(c.Options)
+	} else {
+		L = NewState(c.Options)
 	}
-	L := NewState(Option)
-	configurer(L)
-	return (&Vm{LState: L}).Snapshot()
+	r := pl.Registry()
+	v := &Vm{LState: L, version: r.Version(), isolation: c.Isolation, pool: pl}
+	BaseMod.preload(L, r)
+	if !c.Manual {
+		if c.Modules == nil {
+			v.version = r.hold()
+			r.PreLoad(L)
+			v.registry = r
+		} else {
+			r.preloadOnly(L, c.Modules)
+		}
+	}
+	if c.Sandbox != nil {
+		c.Sandbox.Apply(L)
+	}
+	return v.Snapshot()
 }
 
-func (pl *VmPool) Put(L *Vm) {
-	if L.IsClosed() {
-		return
+// Put Vm back to pool. The closed Vm is discarded, also the Vm exceeds MaxIdle of PoolOptions or returned after Shutdown.
+//
+// Returns ErrNotInUse if the Vm not taken from this pool, or already returned.
+func (pl *VmPool) Put(L *Vm) error {
+	pl.m.Lock()
+	if L.pool != pl {
+		pl.m.Unlock()
+		return fmt.Errorf("%w: taken from another pool", ErrNotInUse)
 	}
-	// reset stack
-	l := L.Reset()
-	if l == nil {
-		return
+	if !L.inUse {
+		pl.m.Unlock()
+		return fmt.Errorf("%w: already returned", ErrNotInUse)
+	}
+	L.inUse = false
+	pl.m.Unlock()
+	var l *Vm
+	polluted := false
+	if !L.IsClosed() {
+		// reset stack
+		l, polluted = L.reset()
 	}
 	pl.m.Lock()
 	defer pl.m.Unlock()
+	if pl.slots != nil {
+		select {
+		case <-pl.slots:
+		default:
+		}
+	}
+	pl.stats.InUse--
+	if polluted {
+		pl.stats.Polluted++
+	}
+	if l == nil || pl.shutdown || (pl.config.MaxIdle > 0 && len(pl.saved) >= pl.config.MaxIdle) {
+		pl.stats.Discarded++
+		L.Close()
+		return nil
+	}
+	l.idle = time.Now()
 	pl.saved = append(pl.saved, l)
+	pl.evict()
+	return nil
+}
+
+// Stats the counters of pool
+func (pl *VmPool) Stats() PoolStats {
+	pl.m.Lock()
+	defer pl.m.Unlock()
+	s := pl.stats
+	s.Idle = len(pl.saved)
+	return s
 }
 
-// Recycle the pool space to max size
+// Evict close the Vms idle longer than IdleTimeout of PoolOptions
+func (pl *VmPool) Evict() {
+	pl.m.Lock()
+	defer pl.m.Unlock()
+	pl.evict()
+}
+func (pl *VmPool) evict() {
+	if pl.config.IdleTimeout <= 0 {
+		return
+	}
+	n := 0
+	for n < len(pl.saved) && time.Since(pl.saved[n].idle) > pl.config.IdleTimeout {
+		n++
+	}
+	pl.close(n)
+}
+
+// close the n oldest idle Vms
+func (pl *VmPool) close(n int) {
+	for _, L := range pl.saved[:n] {
+		L.Close()
+	}
+	pl.stats.Discarded += uint64(n)
+	pl.saved = append(pl.saved[:0], pl.saved[n:]...)
+}
+
+// evictLoop evict idle Vms until Shutdown
+func (pl *VmPool) evictLoop(interval time.Duration, done chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			pl.Evict()
+		case <-done:
+			return
+		}
+	}
+}
+
+// Recycle the pool space to max size, the oldest idle Vms are closed
 func (pl *VmPool) Recycle(max int) {
+	pl.m.Lock()
+	defer pl.m.Unlock()
 	if len(pl.saved) > max {
-		pl.m.Lock()
-		defer pl.m.Unlock()
-		pl.saved = pl.saved[:max]
+		pl.close(len(pl.saved) - max)
 	}
 }
 
 func (pl *VmPool) Shutdown() {
 	pl.m.Lock()
 	defer pl.m.Unlock()
+	if pl.done != nil {
+		close(pl.done)
+		pl.done = nil
+	}
+	pl.shutdown = true
 	for _, L := range pl.saved {
 		L.Close()
 	}
@@ -343,11 +579,3 @@ func (pl *VmPool) Shutdown() {
 }
 
 // endregion
-func configurer(l *LState) {
-	BaseMod.PreLoad(l)
-	if Auto {
-		for _, module := range modulars {
-			module.PreLoad(l)
-		}
-	}
-}
@@ -1,11 +1,14 @@
 package glu
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/ZenLiuCN/fn"
 	. "github.com/chzyer/test"
 	. "github.com/yuin/gopher-lua"
 	"testing"
+	"time"
 )
 
 var (
@@ -100,3 +103,105 @@ func TestGluI1(t *testing.T) {
 	Equal(LString("YYYYYY"), vm.GetGlobal("__SOME_KEY__"))    // Passed
 	fn.Panic(vm.DoString("assert(__SOME_KEY__ == 'YYYYYY')")) // Passed
 }
+
+func TestBoundedPool(t *testing.T) {
+	p := CreatePoolWithOptions(PoolOptions{
+		MaxSize:     2,
+		MaxIdle:     1,
+		IdleTimeout: 30 * time.Millisecond,
+		WaitTimeout: 20 * time.Millisecond,
+	})
+	defer p.Shutdown()
+	a, b := p.Get(), p.Get()
+	if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrPoolExhausted) {
+		t.Fatal("should exhausted", err)
+	}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		p.Put(b)
+	}()
+	c, err := p.Acquire(context.Background())
+	if err != nil || c != b {
+		t.Fatal("should wait for returned vm", err)
+	}
+	fn.Panic(a.DoString(`polluted=true`))
+	p.Put(a)
+	p.Put(c)
+	if s := p.Stats(); s.Created != 2 || s.Reused != 1 || s.Polluted != 1 || s.Discarded != 1 || s.InUse != 0 || s.Idle != 1 {
+		t.Fatalf("%+v", s)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if s := p.Stats(); s.Idle != 0 || s.Discarded != 2 || !a.IsClosed() {
+		t.Fatalf("idle vm should be evicted %+v", s)
+	}
+}
+
+func TestPutOwnership(t *testing.T) {
+	p := CreatePoolWithOptions(PoolOptions{MaxSize: 2})
+	defer p.Shutdown()
+	o := CreatePoolWithOptions(PoolOptions{MaxSize: 2})
+	defer o.Shutdown()
+	a, b := p.Get(), o.Get()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := p.Put(a); err != nil {
+			t.Error(err)
+		}
+		if err := p.Put(a); !errors.Is(err, ErrNotInUse) {
+			t.Error("double Put should fail", err)
+		}
+		if err := p.Put(b); !errors.Is(err, ErrNotInUse) {
+			t.Error("foreign Put should fail", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("duplicate or foreign Put should not block")
+	}
+	if s := p.Stats(); s.InUse != 0 || s.Idle != 1 {
+		t.Fatalf("%+v", s)
+	}
+	if s := o.Stats(); s.InUse != 1 || s.Idle != 0 {
+		t.Fatalf("foreign vm should be ignored %+v", s)
+	}
+	Put(b)
+	if s := o.Stats(); s.InUse != 0 || s.Idle != 1 {
+		t.Fatalf("should return to owner %+v", s)
+	}
+	c := o.Get()
+	o.Shutdown()
+	if err := o.Put(c); err != nil || !c.IsClosed() || o.Stats().Idle != 0 {
+		t.Fatal("should close vm returned after shutdown", err)
+	}
+}
+
+func TestAcquireFailure(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(NewModule("gone", ``, true)); err != nil {
+		t.Fatal(err)
+	}
+	p := MustNewPool(PoolConfig{PoolOptions: PoolOptions{MaxSize: 1, WaitTimeout: 50 * time.Millisecond}, Registry: r, Modules: []string{"gone"}})
+	defer p.Shutdown()
+	if err := r.Unregister("gone"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrNotExists) {
+			t.Fatal("should return failure of new vm", err)
+		}
+	}
+	if s := p.Stats(); s.InUse != 0 || s.Created != 0 {
+		t.Fatalf("should release slot %+v", s)
+	}
+	q := MustNewPool(PoolConfig{PoolOptions: PoolOptions{MaxSize: 1, WaitTimeout: 50 * time.Millisecond}, Constructor: func(o Options) *LState {
+		panic("boom")
+	}})
+	defer q.Shutdown()
+	for i := 0; i < 2; i++ {
+		if _, err := q.Acquire(context.Background()); err == nil || err.Error() != "boom" {
+			t.Fatal("should return panic of constructor", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package glu
+
+import (
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"sort"
+	"strings"
+)
+
+// propertyInfo registered property of BaseType
+type propertyInfo struct {
+	Help   string
+	Getter LGFunction
+	Setter LGFunction //nil for read only
+}
+
+// AddProperty add property to this type, which is accessed as `v.name` and assigned as `v.name=value`.
+//
+// @getter called with stack (self,name) and must push one value
+//
+// @setter called with stack (self,name,value), nil means read only.
+func (m *BaseType[T]) AddProperty(name string, help string, getter LGFunction, setter LGFunction) Type[T] {
+	if getter == nil {
+		panic(fmt.Errorf("getter of property %s is required", name))
+	}
+	if m.properties == nil {
+		m.properties = make(map[string]propertyInfo)
+	} else if _, ok := m.properties[name]; ok {
+		panic(ErrAlreadyExists)
+	}
+	if _, ok := m.methods[name]; ok {
+		panic(ErrAlreadyExists)
+	}
+	m.properties[name] = propertyInfo{help, getter, setter}
+	return m
+}
+
+// AddPropertyCast add property with prechecked type (only create with NewTypeCast), nil setter means read only.
+func (m *BaseType[T]) AddPropertyCast(name string, help string, getter func(s *LState, v T) LValue, setter func(s *LState, v T, value LValue)) Type[T] {
+	if !m.Cast() {
+		panic("can't use AddPropertyCast for not create with NewTypeCast")
+	}
+	var set LGFunction
+	if setter != nil {
+		set = func(s *LState) int {
+			setter(s, m.CheckSelf(s), s.Get(3))
+			return 0
+		}
+	}
+	return m.AddProperty(name, help, func(s *LState) int {
+		s.Push(getter(s, m.CheckSelf(s)))
+		return 1
+	}, set)
+}
+
+func (m *BaseType[T]) ownProperties() map[string]propertyInfo {
+	return m.properties
+}
+
+// allProperties own and inherited properties
+func (m *BaseType[T]) allProperties() map[string]propertyInfo {
+	if m.parent == nil {
+		return m.properties
+	}
+	r := make(map[string]propertyInfo)
+	for p := superType(m); p != nil; p = p.super() {
+		for s, info := range p.ownProperties() {
+			if _, ok := r[s]; !ok {
+				r[s] = info
+			}
+		}
+	}
+	return r
+}
+
+// buildDispatcher set __index and __newindex which dispatch to methods and properties
+func (m *BaseType[T]) buildDispatcher(l *LState, mt *LTable, methods *LTable, properties map[string]propertyInfo) {
+	name := m.Mod.Name
+	members := make([]string, 0, len(properties))
+	methods.ForEach(func(k LValue, _ LValue) {
+		members = append(members, k.String())
+	})
+	for s := range properties {
+		members = append(members, s)
+	}
+	sort.Strings(members)
+	valid := strings.Join(members, ",")
+	mt.RawSetString("__index", l.NewFunction(func(s *LState) int {
+		key := s.Get(2)
+		if key.Type() == LTString {
+			if f := methods.RawGetString(key.String()); f != LNil {
+				s.Push(f)
+				return 1
+			}
+			if p, ok := properties[key.String()]; ok {
+				return p.Getter(s)
+			}
+		}
+		s.RaiseError("unknown member '%s' of %s, valid members: %s", key, name, valid)
+		return 0
+	}))
+	mt.RawSetString("__newindex", l.NewFunction(func(s *LState) int {
+		key := s.Get(2)
+		if key.Type() == LTString {
+			if p, ok := properties[key.String()]; ok {
+				if p.Setter == nil {
+					s.RaiseError("property '%s' of %s is read only", key, name)
+					return 0
+				}
+				return p.Setter(s)
+			}
+		}
+		s.RaiseError("unknown property '%s' of %s, valid members: %s", key, name, valid)
+		return 0
+	}))
+}
+
+func helpPropertyReg(props map[string]propertyInfo, helps map[string]string, mh *strings.Builder, mod string) {
+	for s, info := range props {
+		ro := ""
+		if info.Setter == nil {
+			ro = "readonly "
+		}
+		if info.Help != "" {
+			helps[s] = fmt.Sprintf("%sproperty %s.%s %s", ro, mod, s, info.Help)
+			mh.WriteString(fmt.Sprintf("%sproperty %s.%s %s\n", ro, mod, s, info.Help))
+		} else {
+			mh.WriteString(fmt.Sprintf("%sproperty %s.%s\n", ro, mod, s))
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package glu
+
+import (
+	"github.com/ZenLiuCN/fn"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+)
+
+type response struct {
+	status int
+	body   string
+}
+
+func init() {
+	t := NewTypeCast(func(a any) (v *response, ok bool) { v, ok = a.(*response); return }, "Res", `response with properties`, true, `()Res`,
+		func(s *LState) *response { return &response{status: 200} })
+	t.AddPropertyCast("statusCode", `int 	 the status code`, func(s *LState, v *response) LValue {
+		return LNumber(v.status)
+	}, nil).
+		AddPropertyCast("body", `string 	 the body`, func(s *LState, v *response) LValue {
+			return LString(v.body)
+		}, func(s *LState, v *response, value LValue) {
+			v.body = value.String()
+		}).
+		AddMethodCast("size", `()int`, func(s *LState, v *response) int {
+			s.Push(LNumber(len(v.body)))
+			return 1
+		})
+	fn.Panic(Register(t))
+}
+
+func TestProperty(t *testing.T) {
+	err := ExecuteCode(`
+		print(Res.help('?'))
+		local r=Res.new()
+		assert(r.statusCode==200)
+		r.body='abc'
+		assert(r.body=='abc' and r:size()==3)
+		local ok,err=pcall(function() r.statusCode=1 end)
+		assert(not ok and string.find(err,'read only'))
+		ok,err=pcall(function() return r.unknown end)
+		assert(not ok and string.find(err,'valid members: body,size,statusCode'))
+		ok,err=pcall(function() r.unknown=1 end)
+		assert(not ok)
+	`, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,23 +1,409 @@
 package glu
 
+import (
+	"fmt"
+	lua "github.com/yuin/gopher-lua"
+	"sort"
+	"strings"
+	"sync"
+)
+
 var (
-	modulars    []Modular
-	moduleNames map[string]struct{}
+	// DefaultRegistry the global registry, used by Register and the default pool
+	DefaultRegistry = NewRegistry()
 	// holder is placeholder for a map set
 	holder = struct{}{}
 )
 
-// Register modular into registry
+// Registry a set of Modulars, which can be attached to a VmPool
+type Registry struct {
+	m        sync.RWMutex
+	modulars []Modular
+	names    map[string]struct{}
+	help     map[string]string //cached module listing of help(), keyed by the preload set
+	version  uint64            //increase when any Modular is registered, unregistered or replaced
+	changes  []change          //names of changed Modulars, compacted to the oldest held version
+	held     map[uint64]int    //count of Vms preloaded each version, see hold
+}
+
+// change record of Register, Unregister or Replace
+type change struct {
+	version uint64
+	name    string
+}
+
+// NewRegistry create an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{}, 8), held: make(map[uint64]int)}
+}
+
+// Register modular into the DefaultRegistry
 func Register(m ...Modular) (err error) {
-	if moduleNames == nil {
-		moduleNames = make(map[string]struct{}, 8)
-	}
+	return DefaultRegistry.Register(m...)
+}
+
+// Register modular into registry.
+//
+// The dependencies declared by Dependent must be registered before or in the same batch,
+// the batch is sorted by dependencies, so modulars are always preloaded after their dependencies.
+func (r *Registry) Register(m ...Modular) (err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	batch := make(map[string]Modular, len(m))
 	for _, mod := range m {
-		if v, ok := moduleNames[mod.GetName()]; ok && v == holder {
+		if v, ok := r.names[mod.GetName()]; ok && v == holder {
+			return ErrAlreadyExists
+		} else if _, ok = batch[mod.GetName()]; ok {
 			return ErrAlreadyExists
 		}
-		modulars = append(modulars, mod)
-		moduleNames[mod.GetName()] = holder
+		batch[mod.GetName()] = mod
+	}
+	sorted, err := r.sort(m, batch)
+	if err != nil {
+		return
+	}
+	for _, mod := range sorted {
+		r.modulars = append(r.modulars, mod)
+		r.names[mod.GetName()] = holder
+		r.changed(mod.GetName())
 	}
+	r.help = nil
 	return
 }
+
+// Unregister remove Modular from DefaultRegistry, see Registry.Unregister
+func Unregister(name string) error {
+	return DefaultRegistry.Unregister(name)
+}
+
+// Replace swap Modular in DefaultRegistry, see Registry.Replace
+func Replace(m Modular) error {
+	return DefaultRegistry.Replace(m)
+}
+
+// Unregister remove Modular by name. The pooled Vm will drop the module on next Get.
+//
+// Returns ErrNotExists if not registered, ErrHasDependents if other Modulars depend on it.
+func (r *Registry) Unregister(name string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if _, ok := r.names[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotExists, name)
+	}
+	for _, mod := range r.modulars {
+		for _, dep := range dependencies(mod) {
+			if dep == name {
+				return fmt.Errorf("%w: %s required by %s", ErrHasDependents, name, mod.GetName())
+			}
+		}
+	}
+	for i, mod := range r.modulars {
+		if mod.GetName() == name {
+			r.modulars = append(r.modulars[:i:i], r.modulars[i+1:]...)
+			break
+		}
+	}
+	delete(r.names, name)
+	r.changed(name)
+	return nil
+}
+
+// Replace swap the registered Modular with same name. The pooled Vm will load the new definition on next Get.
+//
+// Returns ErrNotExists if not registered, ErrMissingDependency if dependencies not registered,
+// ErrDependencyCycle if the new dependencies make a cycle.
+func (r *Registry) Replace(m Modular) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	name := m.GetName()
+	if _, ok := r.names[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotExists, name)
+	}
+	all := make([]Modular, len(r.modulars))
+	batch := make(map[string]Modular, len(r.modulars))
+	for i, mod := range r.modulars {
+		if mod.GetName() == name {
+			mod = m
+		}
+		all[i] = mod
+		batch[mod.GetName()] = mod
+	}
+	sorted, err := r.sort(all, batch)
+	if err != nil {
+		return err
+	}
+	r.modulars = sorted
+	r.changed(name)
+	return nil
+}
+
+// sort the Modulars of batch by dependencies, the dependencies not in batch must be registered
+func (r *Registry) sort(m []Modular, batch map[string]Modular) (sorted []Modular, err error) {
+	sorted = make([]Modular, 0, len(m))
+	state := make(map[string]int, len(m)) //1 visiting 2 visited
+	var visit func(mod Modular, path []string) error
+	visit = func(mod Modular, path []string) error {
+		name := mod.GetName()
+		path = append(path, name)
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(path, " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range dependencies(mod) {
+			if d, ok := batch[dep]; ok {
+				if err := visit(d, path); err != nil {
+					return err
+				}
+			} else if _, ok = r.names[dep]; !ok {
+				return fmt.Errorf("%w: %s requires %s", ErrMissingDependency, name, dep)
+			}
+		}
+		state[name] = 2
+		sorted = append(sorted, mod)
+		return nil
+	}
+	for _, mod := range m {
+		if err = visit(mod, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Version the version of registry, increased on each Register, Unregister or Replace
+func (r *Registry) Version() uint64 {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.version
+}
+
+func (r *Registry) changed(name string) {
+	r.version++
+	r.changes = append(r.changes, change{r.version, name})
+	r.help = nil
+	r.compact()
+}
+
+// compact drop the changes not needed by any Vm
+func (r *Registry) compact() {
+	oldest := r.version
+	for v := range r.held {
+		if v < oldest {
+			oldest = v
+		}
+	}
+	n := 0
+	for n < len(r.changes) && r.changes[n].version <= oldest {
+		n++
+	}
+	if n > 0 {
+		r.changes = append(r.changes[:0], r.changes[n:]...)
+	}
+}
+
+// hold the current version by a Vm preloaded all Modulars, the changes since are kept until released
+func (r *Registry) hold() uint64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.held[r.version]++
+	return r.version
+}
+
+// release the version held by a Vm
+func (r *Registry) release(v uint64) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.held[v]--; r.held[v] <= 0 {
+		delete(r.held, v)
+	}
+	r.compact()
+}
+
+// advance the names changed after version v held by a Vm and current Modulars of those names, the hold moves to current version
+func (r *Registry) advance(v uint64) (ver uint64, names []string, mods []Modular) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	seen := make(map[string]struct{})
+	for _, c := range r.changes {
+		if c.version <= v {
+			continue
+		}
+		if _, ok := seen[c.name]; !ok {
+			seen[c.name] = holder
+			names = append(names, c.name)
+		}
+	}
+	for _, mod := range r.modulars {
+		if _, ok := seen[mod.GetName()]; ok {
+			mods = append(mods, mod)
+		}
+	}
+	r.held[r.version]++
+	if r.held[v]--; r.held[v] <= 0 {
+		delete(r.held, v)
+	}
+	r.compact()
+	return r.version, names, mods
+}
+
+// preloadTable the package.preload of LState, the global package may be replaced by Sandbox
+func preloadTable(l *lua.LState) *lua.LTable {
+	pkg, ok := l.G.Global.RawGetString(lua.LoadLibName).(*lua.LTable)
+	if loaded, _ := l.G.Registry.RawGetString("_LOADED").(*lua.LTable); loaded != nil {
+		if t, found := loaded.RawGetString(lua.LoadLibName).(*lua.LTable); found {
+			pkg, ok = t, true
+		}
+	}
+	if !ok {
+		panic(fmt.Errorf("package library not loaded"))
+	}
+	return pkg.RawGetString("preload").(*lua.LTable)
+}
+
+// sync drop changed modules and preload the current definitions
+func (s *Vm) sync(r *Registry) {
+	if s.registry != r || s.version == r.Version() {
+		return
+	}
+	ver, names, mods := r.advance(s.version)
+	preload := preloadTable(s.LState)
+	loaded, _ := s.G.Registry.RawGetString("_LOADED").(*lua.LTable)
+	for _, name := range names {
+		preload.RawSetString(name, lua.LNil)
+		if loaded != nil {
+			loaded.RawSetString(name, lua.LNil)
+		}
+		if s.G.Global.RawGetString(name).Type() == lua.LTTable {
+			s.G.Global.RawSetString(name, lua.LNil)
+		}
+	}
+	var dropMeta func(d ModuleDoc)
+	dropMeta = func(d ModuleDoc) {
+		if d.Type {
+			s.G.Registry.RawSetString(d.Name, lua.LNil)
+		}
+		for _, sub := range d.Submodules {
+			dropMeta(sub)
+		}
+	}
+	for _, mod := range mods {
+		dropMeta(Document(mod))
+		mod.PreLoad(s.LState)
+	}
+	s.version = ver
+	s.Snapshot()
+}
+
+func dependencies(m Modular) []string {
+	if d, ok := m.(Dependent); ok {
+		return d.Dependencies()
+	}
+	return nil
+}
+
+// Modulars registered Modulars in register order
+func (r *Registry) Modulars() []Modular {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return append([]Modular(nil), r.modulars...)
+}
+
+// Has check if a Modular with name is registered
+func (r *Registry) Has(name string) bool {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	_, ok := r.names[name]
+	return ok
+}
+
+// PreLoad load all Modulars into LState
+func (r *Registry) PreLoad(l *lua.LState) {
+	for _, module := range r.Modulars() {
+		module.PreLoad(l)
+	}
+}
+
+// preloadOnly load the named Modulars and their dependencies into LState
+func (r *Registry) preloadOnly(l *lua.LState, names []string) {
+	mods := r.Modulars()
+	byName := make(map[string]Modular, len(mods))
+	for _, mod := range mods {
+		byName[mod.GetName()] = mod
+	}
+	selected := make(map[string]struct{}, len(names))
+	var visit func(name string)
+	visit = func(name string) {
+		if _, ok := selected[name]; ok {
+			return
+		}
+		mod, ok := byName[name]
+		if !ok {
+			panic(fmt.Errorf("%w: %s", ErrNotExists, name))
+		}
+		selected[name] = holder
+		for _, dep := range dependencies(mod) {
+			visit(dep)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	for _, mod := range mods {
+		if _, ok := selected[mod.GetName()]; ok {
+			mod.PreLoad(l)
+		}
+	}
+}
+
+// helpModules the module listing for help() without topic, cached by the preload set of the LState
+func (r *Registry) helpModules(l *lua.LState) string {
+	keys := make([]string, 0)
+	preloadTable(l).
+		ForEach(func(k lua.LValue, _ lua.LValue) {
+			keys = append(keys, k.String())
+		})
+	sort.Strings(keys)
+	key := strings.Join(keys, "\n")
+	r.m.RLock()
+	if h, ok := r.help[key]; ok {
+		defer r.m.RUnlock()
+		return h
+	}
+	r.m.RUnlock()
+	sub := new(strings.Builder)
+	sub.WriteString(HelpHelp)
+	sub.WriteString("\nPreload modules:\n")
+	for _, k := range keys {
+		sub.WriteString(k + "\n")
+	}
+	sub.WriteString("\nLoaded modules:\n")
+	r.m.Lock()
+	defer r.m.Unlock()
+out:
+	for name := range r.names {
+		for _, n := range keys {
+			if n == name {
+				continue out
+			}
+		}
+		sub.WriteString(name + "\n")
+	}
+	deps := new(strings.Builder)
+	for _, mod := range r.modulars {
+		if d := dependencies(mod); len(d) > 0 {
+			deps.WriteString(mod.GetName() + " -> " + strings.Join(d, ",") + "\n")
+		}
+	}
+	if deps.Len() > 0 {
+		sub.WriteString("\nDependencies:\n")
+		sub.WriteString(deps.String())
+	}
+	if r.help == nil {
+		r.help = make(map[string]string)
+	}
+	r.help[key] = sub.String()
+	return r.help[key]
+}
@@ -0,0 +1,181 @@
+package glu
+
+import (
+	"errors"
+	. "github.com/yuin/gopher-lua"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	admin := NewRegistry()
+	shared := NewRegistry()
+	tenant := NewRegistry()
+	mod := NewModule("adminOnly", `only for admin`, true).
+		AddFunc("ping", `()string`, func(s *LState) int {
+			s.Push(LString("pong"))
+			return 1
+		})
+	if err := admin.Register(mod); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Register(mod); err != ErrAlreadyExists {
+		t.Fatal("should be duplicated")
+	}
+	if err := shared.Register(mod); err != nil || !shared.Has("adminOnly") {
+		t.Fatal("should be registered in another registry")
+	}
+	if DefaultRegistry.Has("adminOnly") || tenant.Has("adminOnly") || !admin.Has("adminOnly") {
+		t.Fatal("registry should be isolated")
+	}
+	ap := CreatePoolWithRegistry(admin)
+	defer ap.Shutdown()
+	tp := CreatePoolWithRegistry(tenant)
+	defer tp.Shutdown()
+	a := ap.Get()
+	defer ap.Put(a)
+	if err := a.DoString(`assert(require('adminOnly').ping()=='pong') return help()`); err != nil {
+		t.Fatal(err)
+	}
+	if h := a.ToString(-1); !strings.Contains(h, "adminOnly") || strings.Contains(h, "fnTest") {
+		t.Fatal(h)
+	}
+	v := tp.Get()
+	defer tp.Put(v)
+	if err := v.DoString(`require('adminOnly')`); err == nil {
+		t.Fatal("should not found module")
+	}
+	mp := MustNewPool(PoolConfig{Registry: admin, Manual: true})
+	defer mp.Shutdown()
+	m := mp.Get()
+	defer mp.Put(m)
+	if err := m.DoString(`return help()`); err != nil {
+		t.Fatal(err)
+	}
+	if h := m.ToString(-1); strings.Contains(h, "Preload modules:\nadminOnly") || !strings.Contains(h, "Loaded modules:\nadminOnly") {
+		t.Fatal("help should follow the preload set of the vm", h)
+	}
+}
+
+func TestRegistryDependency(t *testing.T) {
+	r := NewRegistry()
+	a := NewModule("a", ``, true)
+	b := NewModule("b", ``, true).DependsOn("a")
+	c := NewModule("c", ``, true).DependsOn("b", "a")
+	if err := r.Register(b); !errors.Is(err, ErrMissingDependency) {
+		t.Fatal("should missing dependency", err)
+	}
+	if err := r.Register(c, b, a); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range r.Modulars() {
+		names = append(names, m.GetName())
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Fatal("should sorted by dependencies", names)
+	}
+	x := NewModule("x", ``, true).DependsOn("y")
+	y := NewModule("y", ``, true).DependsOn("x")
+	if err := NewRegistry().Register(x, y); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatal("should have cycle", err)
+	} else {
+		t.Log(err)
+	}
+	if err := r.Replace(NewModule("a", ``, true).DependsOn("c")); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatal("replace should detect cycle", err)
+	}
+	if err := r.Register(NewModule("d", ``, true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Replace(NewModule("a", ``, true).DependsOn("d")); err != nil {
+		t.Fatal(err)
+	}
+	names = names[:0]
+	for _, m := range r.Modulars() {
+		names = append(names, m.GetName())
+	}
+	if strings.Join(names, ",") != "d,a,b,c" {
+		t.Fatal("should sorted after replace", names)
+	}
+	p := CreatePoolWithRegistry(r)
+	defer p.Shutdown()
+	v := p.Get()
+	defer p.Put(v)
+	if err := v.DoString(`return help()`); err != nil {
+		t.Fatal(err)
+	}
+	if h := v.ToString(-1); !strings.Contains(h, "c -> b,a") {
+		t.Fatal(h)
+	}
+}
+
+func TestRegistryReplace(t *testing.T) {
+	r := NewRegistry()
+	version := func(v string) Module {
+		return NewModule("feature", ``, true).AddFunc("version", `()string`, func(s *LState) int {
+			s.Push(LString(v))
+			return 1
+		})
+	}
+	if err := r.Register(version("v1"), NewModule("user", ``, true).DependsOn("feature")); err != nil {
+		t.Fatal(err)
+	}
+	p := CreatePoolWithRegistry(r)
+	defer p.Shutdown()
+	check := func(code string) {
+		v := p.Get()
+		defer p.Put(v)
+		if err := v.DoString(code); err != nil {
+			t.Fatal(code, err)
+		}
+	}
+	check(`assert(require('feature').version()=='v1')`)
+	if err := r.Replace(version("v2")); err != nil {
+		t.Fatal(err)
+	}
+	check(`assert(require('feature').version()=='v2')`)
+	if err := r.Unregister("feature"); !errors.Is(err, ErrHasDependents) {
+		t.Fatal("should have dependents", err)
+	}
+	if err := r.Unregister("user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Unregister("feature"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Replace(version("v3")); !errors.Is(err, ErrNotExists) {
+		t.Fatal("should not exists", err)
+	}
+	check(`assert(not pcall(require,'feature'))`)
+	typ := func(v string) Modular {
+		return NewSimpleType[string]("Feature", ``, true).AddFunc("version", `()string`, func(s *LState) int {
+			s.Push(LString(v))
+			return 1
+		})
+	}
+	if err := r.Register(typ("v1")); err != nil {
+		t.Fatal(err)
+	}
+	check(`assert(Feature.version()=='v1')`)
+	if err := r.Replace(typ("v2")); err != nil {
+		t.Fatal(err)
+	}
+	check(`assert(Feature.version()=='v2')`)
+	if n := len(r.changes); n != 0 {
+		t.Fatal("changes should be compacted to the version of pooled vm", n)
+	}
+	v := p.Get()
+	if err := r.Replace(typ("v3")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.changes); n != 1 {
+		t.Fatal("changes should be kept for the pooled vm", n)
+	}
+	v.LState.Close()
+	p.Put(v)
+	p.Shutdown()
+	if len(r.changes) != 0 || len(r.held) != 0 {
+		t.Fatal("changes should be dropped without vm", r.changes, r.held)
+	}
+}
@@ -0,0 +1,313 @@
+package glu
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/yuin/gopher-lua"
+	"strings"
+)
+
+// ErrSandbox the violation of Sandbox policy, raised in LState with this message prefix
+var ErrSandbox = errors.New("sandbox")
+
+var (
+	// UnsafeFunctions the functions or libraries which can touch the host, used by SandboxSafe
+	UnsafeFunctions = []string{
+		"os.execute", "os.exit", "os.remove", "os.rename", "os.setenv", "os.tmpname",
+		IoLibName, DebugLibName,
+		"dofile", "loadfile", "loadstring", "load",
+		"package.loadlib",
+	}
+	// SandboxSafe the policy denies UnsafeFunctions, requires from package.preload only and protects string, table and package libraries
+	SandboxSafe = &Sandbox{
+		Deny:        UnsafeFunctions,
+		ReadOnly:    []string{StringLibName, TabLibName},
+		PreloadOnly: true,
+	}
+)
+
+// Sandbox declarative policy applied to each Vm created by a VmPool, see VmPool.WithSandbox.
+//
+// Names are global functions (`dofile`), library functions (`os.execute`) or whole libraries (`io`).
+type Sandbox struct {
+	Deny     []string //functions or libraries to remove, take precedence over Allow
+	Allow    []string //library functions to keep, other functions of the same library are removed
+	Require  []string //module names allowed to require, standard libraries are always allowed. empty for no restriction, package.preload, package.loaded and package.loaders are filtered too
+	ReadOnly []string //libraries to protect from modification, rawset on them is refused too
+	// PreloadOnly require loads modules from package.preload only, the loaders searching package.path are removed
+	PreloadOnly bool
+}
+
+// Apply the policy to LState, the removed functions raise an error with ErrSandbox prefix when called
+func (p *Sandbox) Apply(l *LState) {
+	loaded, _ := l.G.Registry.RawGetString("_LOADED").(*LTable)
+	libraries := make(map[string][]string)
+	for _, name := range p.Allow {
+		if lib, fun, ok := strings.Cut(name, "."); ok {
+			libraries[lib] = append(libraries[lib], fun)
+		}
+	}
+	for lib, allowed := range libraries {
+		if t, ok := l.G.Global.RawGetString(lib).(*LTable); ok {
+			var removed []string
+			t.ForEach(func(k LValue, v LValue) {
+				if v.Type() == LTFunction && !contains(allowed, k.String()) {
+					removed = append(removed, k.String())
+				}
+			})
+			for _, fun := range removed {
+				t.RawSetString(fun, sandboxDenied(l, lib+"."+fun))
+			}
+		}
+	}
+	for _, name := range p.Deny {
+		if lib, fun, ok := strings.Cut(name, "."); ok {
+			if t, ok := l.G.Global.RawGetString(lib).(*LTable); ok {
+				t.RawSetString(fun, sandboxDenied(l, name))
+			}
+			continue
+		}
+		if t, ok := l.G.Global.RawGetString(name).(*LTable); ok {
+			proxy := sandboxProxy(l, t, name, true)
+			l.G.Global.RawSetString(name, proxy)
+			if loaded != nil && loaded.RawGetString(name) == t {
+				loaded.RawSetString(name, proxy)
+			}
+		} else if l.G.Global.RawGetString(name) != LNil {
+			l.G.Global.RawSetString(name, sandboxDenied(l, name))
+		}
+	}
+	if p.PreloadOnly {
+		if loaders, ok := l.G.Registry.RawGetString("_LOADERS").(*LTable); ok {
+			for i := loaders.Len(); i > 1; i-- {
+				loaders.RawSetInt(i, LNil)
+			}
+		}
+	}
+	protected := make(map[*LTable]string)
+	if len(p.Require) > 0 || p.PreloadOnly {
+		if proxy := p.restrictRequire(l); proxy != nil {
+			protected[proxy] = LoadLibName
+		}
+	}
+	for _, name := range p.ReadOnly {
+		t, ok := l.G.Global.RawGetString(name).(*LTable)
+		if !ok {
+			continue
+		}
+		proxy := sandboxProxy(l, t, name, false)
+		protected[proxy] = name
+		l.G.Global.RawSetString(name, proxy)
+		if loaded != nil && loaded.RawGetString(name) == t {
+			loaded.RawSetString(name, proxy)
+		}
+		if name == StringLibName {
+			//string methods are looked up via the builtin metatable
+			mt := l.NewTable()
+			mt.RawSetString("__index", proxy)
+			mt.RawSetString("__metatable", LString(ErrSandbox.Error()))
+			l.SetMetatable(LString(""), mt)
+		}
+	}
+	if len(protected) > 0 {
+		protectRawSet(l, protected)
+	}
+}
+
+// protectRawSet replace global rawset with the one refuses to modify the read only proxies
+func protectRawSet(l *LState, protected map[*LTable]string) {
+	rawset := l.G.Global.RawGetString("rawset")
+	if rawset.Type() != LTFunction {
+		return
+	}
+	l.G.Global.RawSetString("rawset", l.NewFunction(func(s *LState) int {
+		if name, ok := protected[s.CheckTable(1)]; ok {
+			s.RaiseError("%s: %s is read only", ErrSandbox, name)
+			return 0
+		}
+		top := s.GetTop()
+		s.Push(rawset)
+		for i := 1; i <= top; i++ {
+			s.Push(s.Get(i))
+		}
+		s.Call(top, MultRet)
+		return s.GetTop() - top
+	}))
+}
+
+// restrictRequire replace global require with the allow-list checked one,
+// and the global package with a read only proxy which hides the modules not allowed in preload, loaded and loaders.
+// Returns the proxy, nil if package library not loaded.
+func (p *Sandbox) restrictRequire(l *LState) *LTable {
+	require := l.G.Global.RawGetString("require")
+	pkg, ok := l.G.Global.RawGetString(LoadLibName).(*LTable)
+	if require.Type() != LTFunction || !ok {
+		return nil
+	}
+	allowed := func(name string) bool {
+		return len(p.Require) == 0 || contains(p.Require, name) || isStandardLib(name)
+	}
+	proxy := p.packageProxy(l, pkg, allowed)
+	l.G.Global.RawSetString(LoadLibName, proxy)
+	l.G.Global.RawSetString("require", l.NewFunction(func(s *LState) int {
+		name := s.CheckString(1)
+		if !allowed(name) {
+			s.RaiseError("%s: require '%s' is not allowed, allowed modules: %s", ErrSandbox, name, strings.Join(p.Require, ","))
+			return 0
+		}
+		if name == LoadLibName {
+			s.Push(proxy)
+			return 1
+		}
+		top := s.GetTop()
+		s.Push(require)
+		s.Push(LString(name))
+		s.Call(1, MultRet)
+		return s.GetTop() - top
+	}))
+	return proxy
+}
+
+// packageProxy the proxy of package library, the original table is kept in _LOADED for Go side.
+func (p *Sandbox) packageProxy(l *LState, pkg *LTable, allowed func(name string) bool) *LTable {
+	proxy := l.NewTable()
+	filtered := func(field string, get func(s *LState, t *LTable, name string) LValue) *LTable {
+		t := l.NewTable()
+		mt := l.NewTable()
+		mt.RawSetString("__index", l.NewFunction(func(s *LState) int {
+			src, ok := pkg.RawGetString(field).(*LTable)
+			if !ok || s.Get(2).Type() != LTString {
+				s.Push(LNil)
+				return 1
+			}
+			s.Push(get(s, src, s.ToString(2)))
+			return 1
+		}))
+		mt.RawSetString("__newindex", l.NewFunction(func(s *LState) int {
+			s.RaiseError("%s: %s.%s is read only", ErrSandbox, LoadLibName, field)
+			return 0
+		}))
+		mt.RawSetString("__metatable", LString(ErrSandbox.Error()))
+		l.SetMetatable(t, mt)
+		return t
+	}
+	fields := map[string]*LTable{
+		"preload": filtered("preload", func(s *LState, t *LTable, name string) LValue {
+			if !allowed(name) {
+				return LNil
+			}
+			return t.RawGetString(name)
+		}),
+		"loaded": filtered("loaded", func(s *LState, t *LTable, name string) LValue {
+			if name == LoadLibName {
+				return proxy
+			} else if !allowed(name) {
+				return LNil
+			}
+			return t.RawGetString(name)
+		}),
+	}
+	loaders := l.NewTable()
+	if src, ok := pkg.RawGetString("loaders").(*LTable); ok {
+		for i := 1; i <= src.Len(); i++ {
+			loader := src.RawGetInt(i)
+			loaders.RawSetInt(i, l.NewFunction(func(s *LState) int {
+				if name := s.CheckString(1); !allowed(name) {
+					s.Push(LString(fmt.Sprintf("%s: module '%s' is not allowed", ErrSandbox, name)))
+					return 1
+				}
+				s.Push(loader)
+				s.Push(s.Get(1))
+				s.Call(1, 1)
+				return 1
+			}))
+		}
+	}
+	fields["loaders"] = loaders
+	mt := l.NewTable()
+	mt.RawSetString("__index", l.NewFunction(func(s *LState) int {
+		k := s.Get(2)
+		if t, ok := fields[k.String()]; ok && k.Type() == LTString {
+			s.Push(t)
+			return 1
+		}
+		s.Push(pkg.RawGet(k))
+		return 1
+	}))
+	mt.RawSetString("__newindex", l.NewFunction(func(s *LState) int {
+		s.RaiseError("%s: %s.%s is read only", ErrSandbox, LoadLibName, s.ToString(2))
+		return 0
+	}))
+	mt.RawSetString("__metatable", LString(ErrSandbox.Error()))
+	l.SetMetatable(proxy, mt)
+	return proxy
+}
+
+// sandboxDenied the placeholder of denied function
+func sandboxDenied(l *LState, name string) *LFunction {
+	return l.NewFunction(func(s *LState) int {
+		s.RaiseError("%s: %s is denied", ErrSandbox, name)
+		return 0
+	})
+}
+
+// sandboxProxy create proxy of library table, if denied any access raise error, else modification raise error
+func sandboxProxy(l *LState, t *LTable, name string, denied bool) *LTable {
+	proxy := l.NewTable()
+	mt := l.NewTable()
+	if denied {
+		mt.RawSetString("__index", l.NewFunction(func(s *LState) int {
+			s.RaiseError("%s: %s.%s is denied", ErrSandbox, name, s.ToString(2))
+			return 0
+		}))
+	} else {
+		//copy the members, so the original table is unreachable
+		data := l.NewTable()
+		t.ForEach(func(k LValue, v LValue) {
+			if k != LString("__index") {
+				data.RawSet(k, v)
+			}
+		})
+		mt.RawSetString("__index", data)
+	}
+	mt.RawSetString("__newindex", l.NewFunction(func(s *LState) int {
+		s.RaiseError("%s: %s is read only", ErrSandbox, name)
+		return 0
+	}))
+	mt.RawSetString("__metatable", LString(ErrSandbox.Error()))
+	l.SetMetatable(proxy, mt)
+	return proxy
+}
+
+func isStandardLib(name string) bool {
+	for _, b := range libs {
+		if b.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSandbox attach Sandbox policy to the pool, the pooled Vms created before are closed.
+// @fluent
+func (pl *VmPool) WithSandbox(p *Sandbox) *VmPool {
+	pl.m.Lock()
+	defer pl.m.Unlock()
+	pl.close(len(pl.saved))
+	pl.config.Sandbox = p
+	return pl
+}
+
+// Sandbox the Sandbox policy of this pool, nil if not sandboxed
+func (pl *VmPool) Sandbox() *Sandbox {
+	return pl.config.Sandbox
+}
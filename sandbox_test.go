@@ -0,0 +1,106 @@
+package glu
+
+import (
+	. "github.com/yuin/gopher-lua"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(
+		NewModule("public", ``, true).AddFunc("ping", `()string`, func(s *LState) int {
+			s.Push(LString("pong"))
+			return 1
+		}),
+		NewModule("private", ``, true),
+	); err != nil {
+		t.Fatal(err)
+	}
+	p := CreatePoolWithRegistry(r).WithSandbox(&Sandbox{
+		Deny:     UnsafeFunctions,
+		Allow:    []string{"os.time", "os.clock"},
+		Require:  []string{"public"},
+		ReadOnly: []string{StringLibName, TabLibName},
+	})
+	defer p.Shutdown()
+	for code, violation := range map[string]string{
+		`return os.time()>0 and os.clock()>=0`:     "",
+		`return require('public').ping()=='pong'`:  "",
+		`return ('abc'):upper()=='ABC'`:            "",
+		`return string.format('%d',1)=='1'`:        "",
+		`return table.concat({1,2},',')=='1,2'`:    "",
+		`os.execute('ls')`:                         "os.execute is denied",
+		`os.date()`:                                "os.date is denied",
+		`io.popen('ls')`:                           "io.popen is denied",
+		`dofile('x.lua')`:                          "dofile is denied",
+		`loadstring('return 1')`:                   "loadstring is denied",
+		`require('private')`:                       "require 'private' is not allowed",
+		`string.upper=nil`:                         "string is read only",
+		`table.insert=nil`:                         "table is read only",
+		`getmetatable('').__index.upper=nil`:       "attempt to index",
+		`setmetatable(string,{})`:                  "cannot change a protected metatable",
+		`rawset(string,'upper',1)`:                 "string is read only",
+		`local t={} rawset(t,'a',1) return t.a==1`: "",
+		`package.preload['private']('private')`:    "attempt to call a non-function object",
+		`return package.loaded['private']==nil and package.loaded.package==package`: "",
+		`return type(package.loaders[1]('public'))=='function'`:                     "",
+		`return type(package.loaders[1]('private'))=='string'`:                      "",
+		`return require('package')==package and package.path~=nil`:                  "",
+		`package.preload={}`:               "package.preload is read only",
+		`package.path='./?.lua'`:           "package.path is read only",
+		`rawset(package,'path','./?.lua')`: "package is read only",
+	} {
+		v := p.Get()
+		err := v.DoString(code)
+		if violation == "" {
+			if err != nil || v.Get(-1) != LTrue {
+				t.Fatal(code, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), violation) {
+			t.Fatal(code, "should raise", violation, err)
+		}
+		p.Put(v)
+	}
+}
+
+func TestSandboxSafe(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "leak.lua"), []byte(`return 'leaked'`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code := `package.path='` + filepath.Join(dir, "?.lua") + `' return require('leak')`
+	r := NewRegistry()
+	if err := r.Register(NewModule("public", ``, true)); err != nil {
+		t.Fatal(err)
+	}
+	plain := MustNewPool(PoolConfig{Registry: r})
+	defer plain.Shutdown()
+	v := plain.Get()
+	if err := v.DoString(code); err != nil || v.ToString(-1) != "leaked" {
+		t.Fatal("should load file without sandbox", err)
+	}
+	plain.Put(v)
+	p := MustNewPool(PoolConfig{Registry: r, Sandbox: SandboxSafe})
+	defer p.Shutdown()
+	for code, violation := range map[string]string{
+		code:                            "package.path is read only",
+		`return require('public')~=nil`: "",
+		`return #package.loaders==1`:    "",
+		`require('leak')`:               "module leak not found",
+		`return type(package.loaded.string)=='table'`: "",
+	} {
+		v := p.Get()
+		err := v.DoString(code)
+		if violation == "" {
+			if err != nil || v.Get(-1) != LTrue {
+				t.Fatal(code, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), violation) {
+			t.Fatal(code, "should raise", violation, err)
+		}
+		p.Put(v)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	. "github.com/Jeffail/gabs/v2"
 	"github.com/ZenLiuCN/fn"
 	. "github.com/ZenLiuCN/glu/v3"
+	"github.com/ZenLiuCN/glu/v3/async"
 	"github.com/ZenLiuCN/glu/v3/json"
 	"github.com/jmoiron/sqlx"
 	lua "github.com/yuin/gopher-lua"
@@ -170,6 +171,49 @@ func init() {
 				return json.JSON.New(s, rs)
 			})
 		}).
+		AddMethodCast(`queryAsync`, `(string,JSON?)Future		query database on goroutine, the Future resolves to JSON`, func(s *lua.LState, data *sqlx.DB) int {
+			return async.FuncOf(json.JSON, func(s *lua.LState) func() (*Container, error) {
+				q := CheckString(s, 2)
+				var args any
+				if s.GetTop() == 3 {
+					switch a := json.JSON.Check(s, 3).Data().(type) {
+					case map[string]any, []any:
+						args = a
+					default:
+						s.ArgError(3, "must a json object or json array")
+					}
+				} else if s.GetTop() != 2 {
+					s.ArgError(3, fmt.Sprintf("argument error with %d args", s.GetTop()-1))
+				}
+				return func() (*Container, error) {
+					var r *sqlx.Rows
+					var err error
+					switch a := args.(type) {
+					case map[string]any:
+						r, err = data.NamedQuery(q, a)
+					case []any:
+						r, err = data.Queryx(q, a...)
+					default:
+						r, err = data.Queryx(q)
+					}
+					if err != nil {
+						return nil, fmt.Errorf("query '%s' error :%s", q, err)
+					}
+					defer r.Close()
+					rs := Wrap([]any{})
+					for r.Next() {
+						m := make(map[string]any)
+						if err = r.MapScan(m); err != nil {
+							return nil, err
+						}
+						if err = rs.ArrayAppend(m); err != nil {
+							return nil, err
+						}
+					}
+					return rs, r.Err()
+				}
+			})(s)
+		}).
 		AddMethodCast(`exec`, `(string,JSON?)Result		exec SQL`, func(s *lua.LState, data *sqlx.DB) int {
 			q := CheckString(s, 2)
 			var r sql.Result
@@ -726,5 +770,6 @@ func init() {
 		AddModule(Stmt).
 		AddModule(NamedStmt).
 		AddModule(Result).
-		AddModule(TX)))
+		AddModule(TX).
+		DependsOn(json.MODULE.GetName(), async.MODULE.GetName())))
 }
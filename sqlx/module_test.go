@@ -65,6 +65,11 @@ print('inserts ',tx:execMany('insert into SOME(ti) values(:date)',json.parse('[{
 print('queries ',tx:queryMany('select * from SOME where ti=:date',json.parse('[{"date":"2023-11-02"},{"date":"2023-11-03"}]')):json())
 tx:rollback()
 print('rollback')
+local async=require('async')
+local rows,err=async.awaitAll({db:queryAsync('select * from SOME'),db:queryAsync('select * from SOME where ti>=?',json.parse('["2023-10-02"]'))})
+assert(err==nil and rows[1]:size()==3 and rows[2]:size()==2, err)
+local v,err=db:queryAsync('select * from NOPE'):await()
+assert(v==nil and string.find(err,'NOPE'), err)
 print(db:exec('create table if not exists "SOME1" (ti number)'):rows())
 db:close()
 `, 0, 0, nil, nil); err != nil {
@@ -0,0 +1,447 @@
+package glu
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// stubParam parsed parameter of help signature
+	stubParam struct {
+		Name     string
+		Type     string
+		Optional bool
+		Vararg   bool
+	}
+	// stubFunc parsed help signature
+	stubFunc struct {
+		Params      []stubParam
+		Returns     []string
+		Description string
+	}
+	// stubWriter generate EmmyLua annotations
+	stubWriter struct {
+		b       *strings.Builder
+		classes map[string]string //short type name => class name
+	}
+)
+
+var luaKeywords = map[string]struct{}{
+	"and": holder, "break": holder, "do": holder, "else": holder, "elseif": holder, "end": holder,
+	"false": holder, "for": holder, "function": holder, "goto": holder, "if": holder, "in": holder,
+	"local": holder, "nil": holder, "not": holder, "or": holder, "repeat": holder, "return": holder,
+	"then": holder, "true": holder, "until": holder, "while": holder,
+}
+
+// GenerateStubs write EmmyLua/LuaLS annotation stubs of DefaultRegistry into dir, see Registry.GenerateStubs
+func GenerateStubs(dir string) error {
+	return DefaultRegistry.GenerateStubs(dir)
+}
+
+// GenerateStubs write EmmyLua/LuaLS annotation stubs of all registered Modulars into dir, one file per top level Modular,
+// with an extra glu.lua for the global functions.
+func (r *Registry) GenerateStubs(dir string) error {
+	docs := r.documents()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeStubFile(filepath.Join(dir, "glu.lua"), func(w io.Writer) error {
+		_, err := io.WriteString(w, stubGlobal())
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, d := range docs {
+		d := d
+		if err := writeStubFile(filepath.Join(dir, d.Name+".lua"), func(w io.Writer) error {
+			return WriteStub(w, d, docs...)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func writeStubFile(name string, act func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = act(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteStub write EmmyLua/LuaLS annotation stub of a top level ModuleDoc.
+//
+// @known all ModuleDoc used to resolve type names in help signatures.
+func WriteStub(w io.Writer, d ModuleDoc, known ...ModuleDoc) error {
+	g := &stubWriter{b: new(strings.Builder), classes: make(map[string]string)}
+	for _, k := range known {
+		g.collect(k, "")
+	}
+	g.collect(d, "")
+	fmt.Fprintf(g.b, "---@meta %s\n\n", d.Name)
+	g.module(d, "", !d.Type)
+	if !d.Type {
+		fmt.Fprintf(g.b, "return %s\n", d.Name)
+	}
+	_, err := io.WriteString(w, g.b.String())
+	return err
+}
+
+func stubGlobal() string {
+	return `---@meta glu
+
+---pre compile string into bytecode
+---@param code string
+---@param name string
+---@return userdata? chunk
+---@return string? error
+function chunk(code, name) end
+
+---fetch Help of topic,'?' show topics,without topic show loadable modules
+---@param topic? string
+---@return string?
+function help(topic) end
+`
+}
+
+func (g *stubWriter) collect(d ModuleDoc, prefix string) {
+	name := d.Name
+	if prefix != "" {
+		name = prefix + "." + d.Name
+	}
+	if d.Type {
+		if _, ok := g.classes[d.Name]; !ok {
+			g.classes[d.Name] = name
+		}
+	}
+	for _, sub := range d.Submodules {
+		g.collect(sub, name)
+	}
+}
+
+func (g *stubWriter) module(d ModuleDoc, prefix string, local bool) {
+	name := d.Name
+	if prefix != "" {
+		name = prefix + "." + d.Name
+	}
+	g.comment(d.Help)
+	if d.Extends != "" {
+		fmt.Fprintf(g.b, "---@class %s: %s\n", name, g.luaType(d.Extends))
+	} else {
+		fmt.Fprintf(g.b, "---@class %s\n", name)
+	}
+	for _, f := range d.Fields {
+		fmt.Fprintf(g.b, "---@field %s any %s\n", f.Name, oneLine(f.Help))
+	}
+	for _, f := range d.Properties {
+		h := strings.TrimPrefix(f.Help, "readonly ")
+		typ, desc, _ := strings.Cut(strings.TrimSpace(h), " ")
+		fmt.Fprintf(g.b, "---@field %s %s %s\n", f.Name, g.luaType(typ), oneLine(desc))
+	}
+	if d.Type {
+		for _, op := range d.Operators {
+			g.operator(op)
+		}
+	}
+	if local {
+		fmt.Fprintf(g.b, "local %s = {}\n\n", name)
+	} else {
+		fmt.Fprintf(g.b, "%s = {}\n\n", name)
+	}
+	g.function(name, ".", MemberDoc{Name: HelpFunc, Help: "(topic string?)string? \t fetch help of topic, without topic returns all topics"}, "")
+	if d.Constructor != nil {
+		g.function(name, ".", *d.Constructor, name)
+	}
+	for _, f := range d.Functions {
+		g.function(name, ".", f, "")
+	}
+	for _, f := range d.Methods {
+		g.function(name, ":", f, "")
+	}
+	for _, sub := range d.Submodules {
+		g.module(sub, name, false)
+	}
+}
+
+func (g *stubWriter) operator(op MemberDoc) {
+	var name string
+	switch op.Name {
+	case "__add", "__sub", "__mul", "__div", "__mod", "__pow", "__unm", "__concat", "__len", "__call":
+		name = op.Name[2:]
+	default:
+		return
+	}
+	f, ok := parseSignature(op.Help)
+	if !ok {
+		fmt.Fprintf(g.b, "---@operator %s: any\n", name)
+		return
+	}
+	ret := "any"
+	if len(f.Returns) > 0 {
+		ret = g.luaType(f.Returns[0])
+	}
+	if len(f.Params) > 0 && name != "unm" && name != "len" {
+		fmt.Fprintf(g.b, "---@operator %s(%s): %s\n", name, g.luaType(f.Params[0].Type), ret)
+	} else {
+		fmt.Fprintf(g.b, "---@operator %s: %s\n", name, ret)
+	}
+}
+
+func (g *stubWriter) function(owner, sep string, m MemberDoc, ctor string) {
+	f, ok := parseSignature(m.Help)
+	if m.Info != nil {
+		f = stubFunc{Description: m.Info.Description}
+		for _, p := range m.Info.Params {
+			f.Params = append(f.Params, parseParam(p.Name+" "+p.Type, len(f.Params)))
+			if p.Default != "" {
+				f.Params[len(f.Params)-1].Optional = true
+			}
+		}
+		for _, r := range m.Info.Returns {
+			f.Returns = append(f.Returns, r.Type)
+		}
+		if m.Info.Deprecated != "" {
+			g.comment(f.Description)
+			g.b.WriteString("---@deprecated\n")
+			f.Description = ""
+		}
+		ok = true
+	} else if !ok {
+		f = stubFunc{Description: m.Help, Params: []stubParam{{Name: "...", Type: "any", Vararg: true}}, Returns: []string{"any"}}
+	}
+	g.comment(f.Description)
+	names := make([]string, 0, len(f.Params))
+	for _, p := range f.Params {
+		if p.Vararg {
+			fmt.Fprintf(g.b, "---@param ... %s\n", g.luaType(p.Type))
+			names = append(names, "...")
+			continue
+		}
+		n := p.Name
+		if _, kw := luaKeywords[n]; kw {
+			n += "_"
+		}
+		if p.Optional {
+			fmt.Fprintf(g.b, "---@param %s? %s\n", n, g.luaType(strings.TrimSuffix(p.Type, "?")))
+		} else {
+			fmt.Fprintf(g.b, "---@param %s %s\n", n, g.luaType(p.Type))
+		}
+		names = append(names, n)
+	}
+	if ctor != "" && len(f.Returns) == 0 {
+		f.Returns = []string{ctor}
+	}
+	for _, r := range f.Returns {
+		fmt.Fprintf(g.b, "---@return %s\n", g.luaType(r))
+	}
+	args := strings.Join(names, ", ")
+	if _, kw := luaKeywords[m.Name]; kw {
+		if sep == ":" {
+			args = strings.Join(append([]string{"self"}, names...), ", ")
+		}
+		fmt.Fprintf(g.b, "%s[%q] = function(%s) end\n\n", owner, m.Name, args)
+	} else {
+		fmt.Fprintf(g.b, "function %s%s%s(%s) end\n\n", owner, sep, m.Name, args)
+	}
+}
+
+func (g *stubWriter) comment(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		g.b.WriteString("---")
+		g.b.WriteString(strings.TrimRight(line, " \t\r"))
+		g.b.WriteRune('\n')
+	}
+}
+
+// luaType convert help type to EmmyLua type
+func (g *stubWriter) luaType(t string) string {
+	t = strings.TrimSpace(t)
+	opt := strings.HasSuffix(t, "?")
+	t = strings.TrimSuffix(t, "?")
+	if t == "" {
+		return "any"
+	}
+	var r string
+	if alt := splitTop(t, '|'); len(alt) > 1 {
+		ts := make([]string, 0, len(alt))
+		for _, a := range alt {
+			ts = append(ts, g.luaType(a))
+		}
+		r = strings.Join(ts, "|")
+	} else if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+		e := g.luaType(t[1 : len(t)-1])
+		if strings.ContainsAny(e, "|?") {
+			e = "(" + e + ")"
+		}
+		r = e + "[]"
+	} else {
+		switch t {
+		case "int", "integer":
+			r = "integer"
+		case "bool", "boolean":
+			r = "boolean"
+		case "number", "float", "double":
+			r = "number"
+		case "string", "table", "function", "nil", "userdata", "any", "thread":
+			r = t
+		default:
+			if c, ok := g.classes[t]; ok {
+				r = c
+			} else {
+				r = "any"
+			}
+		}
+	}
+	if opt && r != "any" {
+		if strings.Contains(r, "|") {
+			return r + "|nil"
+		}
+		return r + "?"
+	}
+	return r
+}
+
+// parseSignature parse help signature like `(name string,JSON?)JSON? 	 description`
+func parseSignature(help string) (f stubFunc, ok bool) {
+	h := strings.TrimLeft(help, " \t\r\n")
+	if !strings.HasPrefix(h, "(") {
+		return
+	}
+	end := matchParen(h)
+	if end < 0 {
+		return
+	}
+	params := h[1:end]
+	// drop inline comments of multi-line signature
+	lines := strings.Split(params, "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if j := strings.IndexByte(line, '\t'); j >= 0 {
+			line = line[:j]
+		}
+		lines[i] = line
+	}
+	for _, p := range splitTop(strings.Join(lines, " "), ',') {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		f.Params = append(f.Params, parseParam(p, len(f.Params)))
+	}
+	rest := h[end+1:]
+	var ret string
+	if strings.HasPrefix(rest, "(") {
+		if e := matchParen(rest); e > 0 {
+			ret = rest[1:e]
+			rest = rest[e+1:]
+		}
+	} else if i := strings.IndexAny(rest, " \t\r\n"); i >= 0 {
+		ret = rest[:i]
+		rest = rest[i:]
+	} else {
+		ret = rest
+		rest = ""
+	}
+	for _, r := range splitTop(ret, ',') {
+		if r = strings.TrimSpace(r); r != "" {
+			f.Returns = append(f.Returns, r)
+		}
+	}
+	f.Description = strings.TrimSpace(rest)
+	return f, true
+}
+
+// parseParam parse one param: `type`, `name type`, `name:type`, `type ...`, with optional `=default`
+func parseParam(p string, i int) (r stubParam) {
+	p = strings.TrimSpace(p)
+	if j := strings.IndexByte(p, '='); j >= 0 {
+		p = strings.TrimSpace(p[:j])
+		r.Optional = true
+	}
+	if strings.HasSuffix(p, "...") {
+		r.Vararg = true
+		r.Name = "..."
+		r.Type = strings.TrimSpace(strings.TrimSuffix(p, "..."))
+		if r.Type == "" {
+			r.Type = "any"
+		}
+		return
+	}
+	var name, typ string
+	if j := strings.IndexByte(p, ':'); j >= 0 {
+		name, typ = strings.TrimSpace(p[:j]), strings.TrimSpace(p[j+1:])
+	} else if fs := strings.Fields(p); len(fs) > 1 {
+		name, typ = fs[0], strings.Join(fs[1:], "")
+	} else {
+		typ = p
+	}
+	if name == "" || !isIdentifier(name) {
+		name = fmt.Sprintf("arg%d", i+1)
+	}
+	if strings.HasSuffix(typ, "?") {
+		r.Optional = true
+	}
+	r.Name = name
+	r.Type = typ
+	return
+}
+
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// matchParen index of the parenthesis which closes the leading one
+func matchParen(s string) int {
+	d := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			d++
+		case ')':
+			d--
+			if d == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTop split s by sep which not inside any brackets
+func splitTop(s string, sep rune) (r []string) {
+	d := 0
+	last := 0
+	for i, c := range s {
+		switch c {
+		case '(', '{', '[':
+			d++
+		case ')', '}', ']':
+			d--
+		case sep:
+			if d == 0 {
+				r = append(r, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(r, s[last:])
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,69 @@
+package glu
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSignature(t *testing.T) {
+	f, ok := parseSignature("(pretty boolean=false,ident string='\\t')string 	 json string of the JSON")
+	if !ok || len(f.Params) != 2 || !f.Params[0].Optional || f.Params[1].Name != "ident" || f.Returns[0] != "string" || f.Description != "json string of the JSON" {
+		t.Fatalf("%+v", f)
+	}
+	f, ok = parseSignature("(driver:string,dsn:string)DB 	 connect")
+	if !ok || f.Params[0].Name != "driver" || f.Params[1].Type != "string" || f.Returns[0] != "DB" {
+		t.Fatalf("%+v", f)
+	}
+	f, ok = parseSignature("(JSON,string ...)JSON")
+	if !ok || f.Params[0].Name != "arg1" || !f.Params[1].Vararg || f.Params[1].Type != "string" {
+		t.Fatalf("%+v", f)
+	}
+	f, ok = parseSignature("(code,name string)(Chunk?,string?) ==> pre compile")
+	if !ok || len(f.Returns) != 2 || f.Returns[1] != "string?" {
+		t.Fatalf("%+v", f)
+	}
+	if _, ok = parseSignature("free text"); ok {
+		t.Fatal("should not parse")
+	}
+	g := &stubWriter{b: new(strings.Builder), classes: map[string]string{"JSON": "json.JSON"}}
+	for s, w := range map[string]string{
+		"int":                         "integer",
+		"JSON?":                       "json.JSON?",
+		"{string}?":                   "string[]?",
+		"table|number|string|bool":    "table|number|string|boolean",
+		"int|string?":                 "integer|string|nil",
+		"Unknown":                     "any",
+		"{int|string}":                "(integer|string)[]",
+		"JSON|string|number|bool|nil": "json.JSON|string|number|boolean|nil",
+	} {
+		if r := g.luaType(s); r != w {
+			t.Errorf("luaType(%s)=%s want %s", s, r, w)
+		}
+	}
+}
+
+func TestGenerateStubs(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateStubs(dir); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "Point.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, w := range []string{"---@class Point", "function Point.new() end", "---@param arg1 Point\n---@return Point\nfunction Point:add(arg1) end"} {
+		if !strings.Contains(s, w) {
+			t.Fatalf("missing %q in:\n%s", w, s)
+		}
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "helpTest.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s = string(b); !strings.Contains(s, `helpTest["repeat"] = function(s, n) end`) || !strings.Contains(s, "---@param n? integer") {
+		t.Fatal(s)
+	}
+}
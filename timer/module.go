@@ -0,0 +1,92 @@
+package timer
+
+import (
+	"context"
+	"github.com/ZenLiuCN/fn"
+	. "github.com/ZenLiuCN/glu/v3"
+	. "github.com/yuin/gopher-lua"
+	"time"
+)
+
+var (
+	MODULE Module
+)
+
+func schedule(s *LState, repeat bool) int {
+	f := s.CheckFunction(1)
+	d := time.Duration(s.CheckNumber(2) * LNumber(time.Millisecond))
+	if d < 0 {
+		s.ArgError(2, "delay must not be negative")
+		return 0
+	}
+	args := make([]LValue, 0, s.GetTop()-2)
+	for i := 3; i <= s.GetTop(); i++ {
+		args = append(args, s.Get(i))
+	}
+	interval := time.Duration(0)
+	if repeat {
+		if d == 0 {
+			s.ArgError(2, "interval must greater than 0")
+			return 0
+		}
+		interval = d
+	}
+	s.Push(LNumber(LoopOf(s).Schedule(f, d, interval, args...)))
+	return 1
+}
+
+func init() {
+	MODULE = NewModule("timer", `timer schedule callbacks on the event loop of current VM, the callbacks run by timer.run() or glu.RunLoop.
+local timer=require('timer')
+local n=0
+local id=timer.setInterval(function() n=n+1 end,10)
+timer.setTimeout(function() timer.clear(id) end,55)
+timer.run()
+`, true).
+		AddFunc("setTimeout", `(fn function,ms number,...any)int 	 call function with arguments after milliseconds, returns the timer id`,
+			func(s *LState) int {
+				return schedule(s, false)
+			}).
+		AddFunc("setInterval", `(fn function,ms number,...any)int 	 call function with arguments every milliseconds, returns the timer id`,
+			func(s *LState) int {
+				return schedule(s, true)
+			}).
+		AddFunc("clear", `(id int)bool 	 cancel the timer, returns false if not exists`,
+			func(s *LState) int {
+				s.Push(LBool(LoopOf(s).Clear(s.CheckInt(1))))
+				return 1
+			}).
+		AddFunc("sleep", `(ms number) 	 block current VM for milliseconds`,
+			func(s *LState) int {
+				w := time.NewTimer(time.Duration(s.CheckNumber(1) * LNumber(time.Millisecond)))
+				defer w.Stop()
+				ctx := s.Context()
+				if ctx == nil {
+					<-w.C
+					return 0
+				}
+				select {
+				case <-w.C:
+				case <-ctx.Done():
+					s.RaiseError("sleep interrupted: %s", ctx.Err())
+				}
+				return 0
+			}).
+		AddFunc("pending", `()int 	 count of scheduled timers`,
+			func(s *LState) int {
+				s.Push(LNumber(LoopOf(s).Pending()))
+				return 1
+			}).
+		AddFunc("run", `() 	 run the callbacks until no timers remain`,
+			func(s *LState) int {
+				ctx := s.Context()
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				if err := LoopOf(s).Run(ctx, s); err != nil {
+					s.RaiseError("%s", err)
+				}
+				return 0
+			})
+	fn.Panic(Register(MODULE))
+}
@@ -0,0 +1,53 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	. "github.com/ZenLiuCN/glu/v3"
+	. "github.com/yuin/gopher-lua"
+	"testing"
+	"time"
+)
+
+func TestTimer(t *testing.T) {
+	if err := ExecuteCode(`
+		local timer=require('timer')
+		local order={}
+		local n=0
+		local id=timer.setInterval(function(step) n=n+step end,10,1)
+		timer.setTimeout(function(v) table.insert(order,v) end,30,'b')
+		timer.setTimeout(function(v) table.insert(order,v) end,5,'a')
+		timer.setTimeout(function() timer.clear(id) end,55)
+		local never=timer.setTimeout(function() error('cleared') end,20)
+		assert(timer.clear(never) and not timer.clear(never))
+		assert(timer.pending()==4)
+		timer.run()
+		assert(table.concat(order,',')=='a,b')
+		assert(n>=4 and n<=6, n)
+		assert(timer.pending()==0)
+		timer.sleep(1)
+	`, 0, 0, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunLoop(t *testing.T) {
+	vm := Get()
+	defer Put(vm)
+	if err := vm.DoString(`
+		count=0
+		require('timer').setInterval(function() count=count+1 end,5)
+	`); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	vm.SetContext(ctx)
+	defer vm.RemoveContext()
+	if err := RunLoop(vm); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("should stop by context", err)
+	}
+	if n := vm.GetGlobal("count").(LNumber); n < 5 {
+		t.Fatal("should run callbacks", n)
+	}
+}
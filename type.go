@@ -48,15 +48,35 @@ type Type[T any] interface {
 	Caster() func(any) (T, bool)
 	// AddFunc static function
 	AddFunc(name string, help string, fn LGFunction) Type[T]
+	// AddFuncInfo static function with structured help, the help string is generated by HelpInfo.String
+	AddFuncInfo(name string, info *HelpInfo, fn LGFunction) Type[T]
 
 	// AddField static field
 	AddField(name string, help string, value LValue) Type[T]
 	// AddFieldSupplier static field with supplier
 	AddFieldSupplier(name string, help string, su func(s *LState) LValue) Type[T]
+	// AddEnum static read-only Enum
+	AddEnum(name string, help string, members map[string]any) Type[T]
+	// Enum fetch Enum added by AddEnum
+	Enum(name string) *Enum
 	//AddModule add sub-module
 	AddModule(mod Modular) Type[T]
+	// DependsOn declare top level Modulars this Type depends on
+	DependsOn(names ...string) Type[T]
 	// AddMethod add method to this type which means instance method.
 	AddMethod(name string, help string, value LGFunction) Type[T]
+	// AddMethodInfo add method with structured help, the help string is generated by HelpInfo.String
+	AddMethodInfo(name string, info *HelpInfo, value LGFunction) Type[T]
+
+	// AddProperty add property to this type, which is accessed as `v.name` and assigned as `v.name=value`.
+	//
+	// @getter called with stack (self,name) and must push one value
+	//
+	// @setter called with stack (self,name,value), nil means read only.
+	AddProperty(name string, help string, getter LGFunction, setter LGFunction) Type[T]
+
+	// AddPropertyCast add property with prechecked type (only create with NewTypeCast), nil setter means read only.
+	AddPropertyCast(name string, help string, getter func(s *LState, v T) LValue, setter func(s *LState, v T, value LValue)) Type[T]
 
 	// AddMethodUserData add method to this type which means instance method, with auto extract first argument.
 	AddMethodUserData(name string, help string, act func(s *LState, u *LUserData) int) Type[T]
@@ -81,8 +101,10 @@ type BaseType[T any] struct {
 	caster      func(any) (T, bool)
 	constructor func(*LState) T //Constructor for this BaseType , also can define other Constructor by add functions
 	methods     map[string]funcInfo
-	fields      map[string]fieldInfo
 	operators   map[Operate]funcInfo
+	parent      superType             //the parent type, see Extend
+	subtypes    []func(any) (T, bool) //the casters of extended types, see Extend
+	properties  map[string]propertyInfo
 }
 
 // NewSimpleType create new BaseType without ctor
@@ -112,6 +134,10 @@ func (m *BaseType[T]) GetHelp() string {
 	return m.Mod.GetHelp()
 }
 
+func (m *BaseType[T]) Dependencies() []string {
+	return m.Mod.Dependencies()
+}
+
 func (m *BaseType[T]) prepare() {
 	if m.Mod.prepared {
 		return
@@ -126,9 +152,11 @@ func (m *BaseType[T]) prepare() {
 		mh.WriteRune('\n')
 	}
 	helpFuncReg(m.Mod.functions, help, mh, m.Mod.Name)
-	helpFieldReg(m.fields, help, mh, m.Mod.Name)
+	helpFieldReg(m.Mod.fields, help, mh, m.Mod.Name)
 	helpMethodReg(m.methods, help, mh, m.Mod.Name)
-	helpOperatorReg(m.operators, len(m.methods) > 0, help, mh, m.Mod.Name)
+	helpPropertyReg(m.properties, help, mh, m.Mod.Name)
+	helpOperatorReg(m.operators, len(m.allMethods()) > 0 || len(m.allProperties()) > 0, help, mh, m.Mod.Name)
+	helpInheritedReg(m, help, mh)
 	helpSubModReg(m.Mod.Submodules, help, mh, m.Mod.Name)
 	helpCtorReg(m.constructor, m.HelpCtor, help, mh, m.Mod.Name)
 	if mh.Len() > 0 {
@@ -163,6 +191,12 @@ func (m *BaseType[T]) AddFunc(name string, help string, fn LGFunction) Type[T] {
 
 }
 
+// AddFuncInfo add function to this Modular with structured help
+func (m *BaseType[T]) AddFuncInfo(name string, info *HelpInfo, fn LGFunction) Type[T] {
+	m.Mod.AddFuncInfo(name, info, fn)
+	return m
+}
+
 // AddField add value field to this Modular
 //
 // @name the field name
@@ -179,6 +213,17 @@ func (m *BaseType[T]) AddFieldSupplier(name string, help string, su func(s *LSta
 	return m
 }
 
+// AddEnum add read-only Enum to this Modular
+func (m *BaseType[T]) AddEnum(name string, help string, members map[string]any) Type[T] {
+	m.Mod.AddEnum(name, help, members)
+	return m
+}
+
+// Enum fetch Enum added by AddEnum
+func (m *BaseType[T]) Enum(name string) *Enum {
+	return m.Mod.Enum(name)
+}
+
 // AddModule add sub-module to this Modular
 //
 // @mod the Mod **Note** must with TopLevel false.
@@ -187,6 +232,12 @@ func (m *BaseType[T]) AddModule(mod Modular) Type[T] {
 	return m
 
 }
+
+// DependsOn declare top level Modulars this Type depends on.
+func (m *BaseType[T]) DependsOn(names ...string) Type[T] {
+	m.Mod.DependsOn(names...)
+	return m
+}
 func (m *BaseType[T]) PreLoad(l *LState) {
 	if !m.Mod.Top {
 		return
@@ -218,8 +269,8 @@ func (m *BaseType[T]) getOrBuildMeta(l *LState) *LTable {
 			fn[s] = info.Func
 		}
 	}
-	if len(m.fields) > 0 {
-		for key, value := range m.fields {
+	if len(m.Mod.fields) > 0 {
+		for key, value := range m.Mod.fields {
 			if value.Supplier != nil {
 				l.SetField(mt, key, value.Supplier(l))
 			} else if value.Value != LNil {
@@ -234,56 +285,34 @@ func (m *BaseType[T]) getOrBuildMeta(l *LState) *LTable {
 			t.PreloadSubModule(l, mt)
 		}
 	}
-	if len(m.methods) > 0 {
-		method := make(map[string]LGFunction, len(m.Mod.functions))
-		for s, info := range m.methods {
+	methods := m.allMethods()
+	properties := m.allProperties()
+	if len(methods) > 0 || len(properties) > 0 {
+		method := make(map[string]LGFunction, len(methods))
+		for s, info := range methods {
 			method[s] = info.Func
 		}
-		// methods
-		mt.RawSetString("__index", l.SetFuncs(l.NewTable(), method))
+		if len(properties) > 0 {
+			// methods and properties
+			m.buildDispatcher(l, mt, l.SetFuncs(l.NewTable(), method), properties)
+		} else {
+			// methods
+			mt.RawSetString("__index", l.SetFuncs(l.NewTable(), method))
+		}
 	}
-	if len(m.operators) > 0 {
-		for op, info := range m.operators {
-			var name string
+	if operators := m.allOperators(); len(operators) > 0 {
+		for op, info := range operators {
 			switch op {
-			case OPERATE_ADD:
-				name = "__add"
-			case OPERATE_SUB:
-				name = "__sub"
-			case OPERATE_MUL:
-				name = "__mul"
-			case OPERATE_DIV:
-				name = "__div"
-			case OPERATE_UNM:
-				name = "__unm"
-			case OPERATE_MOD:
-				name = "__mod"
-			case OPERATE_POW:
-				name = "__pow"
-			case OPERATE_CONCAT:
-				name = "__concat"
-			case OPERATE_EQ:
-				name = "__eq"
-			case OPERATE_LT:
-				name = "__lt"
-			case OPERATE_LE:
-				name = "__le"
-			case OPERATE_LEN:
-				name = "__len"
 			case OPERATE_NEWINDEX:
-				name = "__newindex"
-			case OPERATE_TOSTRING:
-				name = "__tostring"
-			case OPERATE_CALL:
-				name = "__call"
+				if len(properties) > 0 {
+					panic(ErrIndexOverrideWithMethods)
+				}
 			case OPERATE_INDEX:
-				if len(m.methods) > 0 {
+				if len(methods) > 0 || len(properties) > 0 {
 					panic(ErrIndexOverrideWithMethods)
 				}
-				name = "__index"
-			default:
-				panic(fmt.Errorf("unsupported operators of %d", op))
 			}
+			name, _ := operatorName(op)
 			l.SetField(mt, name, l.NewFunction(info.Func))
 		}
 	}
@@ -318,7 +347,7 @@ func (m BaseType[T]) new(s *LState) (n int) {
 	val := m.constructor(s)
 	ud := s.NewUserData()
 	ud.Value = val
-	s.SetMetatable(ud, s.GetTypeMetatable(m.Mod.Name))
+	s.SetMetatable(ud, m.getOrBuildMeta(s))
 	s.Push(ud)
 	return 1
 }
@@ -330,7 +359,7 @@ func (m BaseType[T]) Cast() bool {
 
 // Check  cast value on stack
 func (m BaseType[T]) Check(s *LState, n int) T {
-	v, ok := m.caster(s.CheckUserData(n).Value)
+	v, ok := m.cast(s.CheckUserData(n).Value)
 	if !ok {
 		s.ArgError(n, "require type "+m.Mod.Name)
 	}
@@ -338,32 +367,59 @@ func (m BaseType[T]) Check(s *LState, n int) T {
 
 }
 func (m BaseType[T]) CheckUserData(ud *LUserData, s *LState) T {
-	v, ok := m.caster(ud.Value)
+	v, ok := m.cast(ud.Value)
 	if !ok {
 		s.ArgError(1, "require receiver type "+m.Mod.Name)
 	}
 	return v
 }
 func (m BaseType[T]) CheckSelf(s *LState) T {
-	v, ok := m.caster(s.CheckUserData(1).Value)
+	v, ok := m.cast(s.CheckUserData(1).Value)
 	if !ok {
 		s.ArgError(1, "require receiver type "+m.Mod.Name)
 	}
 	return v
 }
+
+// cast the value by caster or the casters of extended types
+func (m BaseType[T]) cast(a any) (v T, ok bool) {
+	if m.caster != nil {
+		if v, ok = m.caster(a); ok {
+			return
+		}
+	}
+	for _, sub := range m.subtypes {
+		if v, ok = sub(a); ok {
+			return
+		}
+	}
+	return
+}
 func (m *BaseType[T]) Caster() func(any) (T, bool) {
 	return m.caster
 }
 
 // AddMethod add method to this type which means instance method.
 func (m *BaseType[T]) AddMethod(name string, help string, value LGFunction) Type[T] {
+	m.addMethod(name, funcInfo{help, value, nil})
+	return m
+}
+
+// AddMethodInfo add method to this type with structured help, the help string is generated by HelpInfo.String
+func (m *BaseType[T]) AddMethodInfo(name string, info *HelpInfo, value LGFunction) Type[T] {
+	m.addMethod(name, funcInfo{info.String(), value, info})
+	return m
+}
+func (m *BaseType[T]) addMethod(name string, info funcInfo) {
 	if m.methods == nil {
 		m.methods = make(map[string]funcInfo)
 	} else if _, ok := m.methods[name]; ok {
 		panic(ErrAlreadyExists)
 	}
-	m.methods[name] = funcInfo{help, value}
-	return m
+	if _, ok := m.properties[name]; ok {
+		panic(ErrAlreadyExists)
+	}
+	m.methods[name] = info
 }
 
 // Override operators an operator
@@ -373,7 +429,7 @@ func (m *BaseType[T]) Override(op Operate, help string, fn LGFunction) Type[T] {
 	} else if _, ok := m.operators[op]; ok {
 		panic(ErrAlreadyExists)
 	}
-	m.operators[op] = funcInfo{help, fn}
+	m.operators[op] = funcInfo{help, fn, nil}
 	return m
 }
 
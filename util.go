@@ -1,12 +1,14 @@
 package glu
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	. "github.com/yuin/gopher-lua"
 	"github.com/yuin/gopher-lua/parse"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // CompileChunk compile code to FunctionProto
@@ -81,66 +83,18 @@ var (
 )
 
 // ExecuteChunk execute pre complied FunctionProto
-func ExecuteChunk(code *FunctionProto, argN, retN int, before Operator, after Operator) (err error) {
-	s := Get()
-	defer Put(s)
-	s.Push(s.NewFunctionFromProto(code))
-	if before != nil {
-		if err = before(s); err != nil {
-			return err
-		}
-	}
-	err = s.PCall(argN, retN, nil)
-	if err != nil {
-		return err
-	}
-	if after != nil {
-		return after(s)
-	}
-	return nil
+func ExecuteChunk(code *FunctionProto, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) (err error) {
+	return ExecuteChunkWithContext(context.Background(), code, argN, retN, before, after, opts...)
 }
 
-func ExecuteFunction(fn *LFunction, argN, retN int, before Operator, after Operator) (err error) {
-	s := Get()
-	defer Put(s)
-	s.Push(fn)
-	if before != nil {
-		if err = before(s); err != nil {
-			return err
-		}
-	}
-	err = s.PCall(argN, retN, nil)
-	if err != nil {
-		return err
-	}
-	if after != nil {
-		return after(s)
-	}
-	return nil
+// ExecuteFunction execute function in LState, use before to push args, after to extract return value
+func ExecuteFunction(fn *LFunction, argN, retN int, before Operator, after Operator, opts ...ExecuteOption) (err error) {
+	return ExecuteFunctionWithContext(context.Background(), fn, argN, retN, before, after, opts...)
 }
 
 // ExecuteCode run code in LState, use before to push args, after to extract return value
-func ExecuteCode(code string, argsN, retN int, before Operator, after Operator) error {
-	s := Get()
-	defer Put(s)
-	if fn, err := s.LoadString(code); err != nil {
-		return err
-	} else {
-		s.Push(fn)
-		if before != nil {
-			if err = before(s); err != nil {
-				return err
-			}
-		}
-		err = s.PCall(argsN, retN, nil)
-		if err != nil {
-			return err
-		}
-		if after != nil {
-			return after(s)
-		}
-		return nil
-	}
+func ExecuteCode(code string, argsN, retN int, before Operator, after Operator, opts ...ExecuteOption) error {
+	return ExecuteCodeWithContext(context.Background(), code, argsN, retN, before, after, opts...)
 }
 
 // TableToSlice convert LTable to a Slice with all Number index values
@@ -307,71 +261,166 @@ func Raw(v LValue) any {
 	}
 }
 
-// Pack any to LValue.
+// Pack any to LValue, Unpack is the inverse.
+//
+// 1. nil, bool, all numeric kinds, string and other Lua value packed as normal LValue
+//
+// 2. []byte packed as string, time.Time packed as seconds since epoch with microsecond precision,
+// the Location is dropped (Unpack returns UTC)
+//
+// 3. array, slice, map with bool, numeric or string keys (also interface keys holding them, such as map[any]any),
+// and struct packed into LTable (the elements also packed),
+// struct fields are mapped by the tags same as Decode, fields tagged with omitempty are skipped when zero
 //
-// 1. nil, bool, numbers and other Lua value packed as normal LValue
+// 4. nil pointers packed as nil, others packed into LUserData (keeps the reference),
+// values of BindType are packed with the bound Type
 //
-// 2. array, slice,map[string]any packed into LTable (the elements also packed)
+// 5. others (and structs without mapped fields) are packed into LUserData
 //
-// 3. others are packed into LUserData
+// **Note** cyclic values panic with ErrCyclicValue.
 func Pack(v any, s *LState) LValue {
-	switch v.(type) {
+	switch x := v.(type) {
 	case nil:
 		return LNil
 	case LValue:
-		return v.(LValue)
+		return x
 	case bool:
-		return LBool(v.(bool))
+		return LBool(x)
 	case string:
-		return LString(v.(string))
-	case *LState:
-		return v.(*LState)
-	case *LFunction:
-		return v.(*LFunction)
-	case *LTable:
-		return v.(*LTable)
-	case *LUserData:
-		return v.(*LUserData)
-	case *LChannel:
-		return v.(*LChannel)
-	default:
-		vv := reflect.ValueOf(v)
-		switch {
-		case vv.Kind() < reflect.Complex64:
-			switch {
-			case vv.CanInt():
-				return LNumber(vv.Int())
-			case vv.CanFloat():
-				return LNumber(vv.Float())
-			default:
-				panic(fmt.Sprintf("unknown : %#v", v))
-			}
-		case vv.Kind() == reflect.Array || vv.Kind() == reflect.Slice:
-			t := s.NewTable()
-			for i := 0; i < vv.Len(); i++ {
-				t.Insert(i, Pack(vv.Index(i).Interface(), s))
+		return LString(x)
+	}
+	return (&packer{s: s, visiting: make(map[packRef]bool)}).pack(reflect.ValueOf(v))
+}
+
+// ErrCyclicValue the value refers to itself, can not be packed
+var ErrCyclicValue = errors.New("cyclic value")
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// packRef the reference of container which may be cyclic
+type packRef struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+type packer struct {
+	s        *LState
+	visiting map[packRef]bool
+}
+
+// enter mark the reference visiting, panic if cyclic
+func (p *packer) enter(v reflect.Value) packRef {
+	r := packRef{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		r.len = v.Len()
+	}
+	if p.visiting[r] {
+		panic(fmt.Errorf("%w: %s", ErrCyclicValue, v.Type()))
+	}
+	p.visiting[r] = true
+	return r
+}
+
+func (p *packer) userData(v reflect.Value) LValue {
+	u := p.s.NewUserData()
+	u.Value = v.Interface()
+	return u
+}
+
+func (p *packer) pack(v reflect.Value) LValue {
+	if !v.IsValid() {
+		return LNil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return LNil
+		}
+	}
+	if x, ok := v.Interface().(LValue); ok {
+		return x
+	}
+	if b, ok := bound.Load(v.Type()); ok {
+		return b.(boundType).pack(p.s, v.Interface())
+	}
+	if v.Type() == timeType {
+		return LNumber(float64(v.Interface().(time.Time).UnixMicro()) / 1e6)
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		return p.pack(v.Elem())
+	case reflect.Bool:
+		return LBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return LNumber(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return LNumber(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return LNumber(v.Float())
+	case reflect.String:
+		return LString(v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return LString(v.Bytes())
+		}
+		defer delete(p.visiting, p.enter(v))
+		fallthrough
+	case reflect.Array:
+		t := p.s.CreateTable(v.Len(), 0)
+		for i := 0; i < v.Len(); i++ {
+			t.RawSetInt(i+1, p.pack(v.Index(i)))
+		}
+		return t
+	case reflect.Map:
+		if packableKeys(v) {
+			defer delete(p.visiting, p.enter(v))
+			t := p.s.CreateTable(0, v.Len())
+			r := v.MapRange()
+			for r.Next() {
+				t.RawSet(p.pack(r.Key()), p.pack(r.Value()))
 			}
 			return t
-		case vv.Kind() == reflect.Map:
-			kt := vv.Type().Key()
-			if (kt.Kind() > reflect.Bool && kt.Kind() < reflect.Complex64) || kt.Kind() == reflect.String {
-				t := s.NewTable()
-				r := vv.MapRange()
-				for r.Next() {
-					k := Pack(r.Key().Interface(), s)
-					val := Pack(r.Value().Interface(), s)
-					t.RawSet(k, val)
+		}
+	case reflect.Struct:
+		if !opaque(v.Type()) {
+			t := p.s.NewTable()
+			for _, f := range structFields(v.Type()) {
+				fv := v.FieldByIndex(f.index)
+				if f.omitempty && fv.IsZero() {
+					continue
 				}
-				return t
+				t.RawSetString(f.name, p.pack(fv))
 			}
-			u := s.NewUserData()
-			u.Value = v
-			return u
-		default:
-			u := s.NewUserData()
-			u.Value = v
-			return u
+			return t
 		}
 	}
+	return p.userData(v)
+}
+
+// packableKeys the keys of map are all bool, numeric or string, include the dynamic values of interface keys
+func packableKeys(v reflect.Value) bool {
+	if scalar(v.Type().Key()) {
+		return true
+	}
+	if v.Type().Key().Kind() != reflect.Interface {
+		return false
+	}
+	r := v.MapRange()
+	for r.Next() {
+		if k := r.Key(); k.IsNil() || !scalar(k.Elem().Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// scalar the bool, numeric or string type
+func scalar(t reflect.Type) bool {
+	return (t.Kind() >= reflect.Bool && t.Kind() < reflect.Complex64) || t.Kind() == reflect.String
+}
 
+// opaque the struct type without mapped fields, packed as LUserData
+func opaque(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && len(structFields(t)) == 0
 }
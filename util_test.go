@@ -5,8 +5,11 @@ import (
 	"fmt"
 	. "github.com/yuin/gopher-lua"
 	"github.com/yuin/gopher-lua/parse"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var (
@@ -223,3 +226,78 @@ func BenchmarkParse(b *testing.B) {
 		}
 	}
 }
+
+type packRecord struct {
+	ID    uint64
+	Small int8
+	Ratio float32
+	Name  string `lua:"title"`
+	Note  string `lua:"note,omitempty"`
+	Data  []byte
+	At    time.Time
+	Next  *packRecord `lua:"next,omitempty"`
+	Tags  map[string]uint
+	Items []int
+}
+
+func TestPack(t *testing.T) {
+	s := Get()
+	defer Put(s)
+	r := packRecord{ID: 1 << 40, Small: -3, Ratio: 0.5, Name: "a", Data: []byte{0, 1, 255}, At: time.Now().Truncate(time.Microsecond),
+		Next: &packRecord{Name: "b", Items: []int{}}, Tags: map[string]uint{"x": 1}, Items: []int{1, 2}}
+	s.SetGlobal("r", Pack(r, s.LState))
+	if err := s.DoString(`
+		assert(r.id==2^40 and r.small==-3 and r.ratio==0.5 and r.title=='a' and r.note==nil)
+		assert(r.data=='\0\1\255' and type(r.at)=='number' and r.tags.x==1 and #r.items==2)
+		assert(type(r.next)=='userdata')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	u, err := Unpack[packRecord](s.GetGlobal("r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.At.Equal(r.At) {
+		t.Fatal("time should round trip", u.At, r.At)
+	}
+	u.At, r.At = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(u, r) {
+		t.Fatalf("should round trip\n%#v\n%#v", u, r)
+	}
+	if v := Pack(uint8(255), s.LState); v != LNumber(255) {
+		t.Fatal("should pack unsigned", v)
+	}
+	if v := Pack((*packRecord)(nil), s.LState); v != LNil {
+		t.Fatal("should pack nil pointer as nil", v)
+	}
+	if v, ok := Pack(&r, s.LState).(*LUserData); !ok || v.Value != &r {
+		t.Fatal("should pack pointer as userdata", v)
+	}
+	if v, ok := Pack(&sync.Mutex{}, s.LState).(*LUserData); !ok || v.Value == nil {
+		t.Fatal("should pack opaque struct as userdata")
+	}
+	if err := s.DoString(`m={1,'a',x=true,[true]=2.5,y={z='w'}}`); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Unpack[any](s.GetGlobal("m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetGlobal("m", Pack(m, s.LState))
+	if err := s.DoString(`assert(m[1]==1 and m[2]=='a' and m.x==true and m[true]==2.5 and m.y.z=='w')`); err != nil {
+		t.Fatal("map[any]any should round trip", m, err)
+	}
+	if v, ok := Pack(map[any]int{struct{}{}: 1}, s.LState).(*LUserData); !ok || v.Value == nil {
+		t.Fatal("should pack map of non scalar keys as userdata")
+	}
+	cyclic := map[string]any{}
+	cyclic["self"] = cyclic
+	func() {
+		defer func() {
+			if e, ok := recover().(error); !ok || !errors.Is(e, ErrCyclicValue) {
+				t.Fatal("should detect cycle", e)
+			}
+		}()
+		Pack(cyclic, s.LState)
+	}()
+}